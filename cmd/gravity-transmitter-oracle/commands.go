@@ -0,0 +1,334 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+
+	app "github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/app/instance"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/config"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/logging"
+	subscriber "github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/subscriber/service"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// Version is set by -ldflags "-X main.Version=..." while building
+var Version = "dev"
+
+type Command struct {
+	Name    string
+	Args    string
+	Summary string
+	NArgs   int
+	Flags   func(*pflag.FlagSet)
+	Run     func(fs *pflag.FlagSet) error
+}
+
+var commands = []*Command{
+	{
+		Name:    "run",
+		Summary: "Receive events from gravity and write them to database (default)",
+		Flags: func(fs *pflag.FlagSet) {
+			gravityFlags(fs)
+			databaseFlags(fs)
+		},
+		Run: runCommand,
+	},
+	{
+		Name:    "check",
+		Summary: "Validate configuration and rules, and verify database and target tables",
+		Flags: func(fs *pflag.FlagSet) {
+			gravityFlags(fs)
+			databaseFlags(fs)
+		},
+		Run: checkCommand,
+	},
+	{
+		Name:    "truncate",
+		Args:    "<table>",
+		Summary: "Truncate table in database",
+		NArgs:   1,
+		Flags: func(fs *pflag.FlagSet) {
+			databaseFlags(fs)
+			fs.Bool("yes", false, "confirm truncating, which cannot be undone")
+		},
+		Run: truncateCommand,
+	},
+	{
+		Name:    "state",
+		Args:    "show|reset",
+		Summary: "Show or reset pipeline and initial load states, transmitter should be stopped",
+		NArgs:   1,
+		Flags: func(fs *pflag.FlagSet) {
+			bindString(fs, "state-store", "subscriber.stateStore", "path of state store")
+			fs.UintSlice("pipeline", nil, "pipelines to be reset, all pipelines if not specified")
+			fs.Uint64("sequence", 0, "sequence which pipelines are reset to")
+			fs.Bool("initial-load", false, "clear states of initial load as well")
+		},
+		Run: stateCommand,
+	},
+	{
+		Name:    "replay",
+		Args:    "<file>",
+		Summary: "Execute statements in dead letter file again",
+		NArgs:   1,
+		Flags: func(fs *pflag.FlagSet) {
+			databaseFlags(fs)
+			fs.String("output", "", "file for entries which cannot be replayed (default \"<file>.failed\")")
+		},
+		Run: replayCommand,
+	},
+	{
+		Name:    "version",
+		Summary: "Print version",
+		Run:     versionCommand,
+	},
+}
+
+// Flags override configuration and environment variables
+func bindString(fs *pflag.FlagSet, name string, key string, usage string) {
+	fs.String(name, "", usage)
+	viper.BindPFlag(key, fs.Lookup(name))
+}
+
+func bindInt(fs *pflag.FlagSet, name string, key string, usage string) {
+	fs.Int(name, 0, usage)
+	viper.BindPFlag(key, fs.Lookup(name))
+}
+
+func gravityFlags(fs *pflag.FlagSet) {
+	bindString(fs, "gravity-host", "gravity.host", "host of gravity")
+	bindString(fs, "subscriber-id", "subscriber.subscriberID", "ID of subscriber")
+	bindString(fs, "state-store", "subscriber.stateStore", "path of state store")
+	bindString(fs, "rules", "rules.subscription", "path of rules file")
+}
+
+func databaseFlags(fs *pflag.FlagSet) {
+	bindString(fs, "driver", "database.driver", "database driver, oci8 or ora")
+	bindString(fs, "connect-string", "database.connectString", "connect string of database")
+	bindString(fs, "db-host", "database.host", "host of database")
+	bindInt(fs, "db-port", "database.port", "port of database")
+	bindString(fs, "service-name", "database.serviceName", "service name of database")
+	bindString(fs, "username", "database.username", "username of database")
+}
+
+func getCommand(name string) *Command {
+
+	for _, cmd := range commands {
+		if cmd.Name == name {
+			return cmd
+		}
+	}
+
+	return nil
+}
+
+func usage() {
+
+	fmt.Fprintf(os.Stderr, "Usage: %s <command> [flags]\n\nCommands:\n", os.Args[0])
+	for _, cmd := range commands {
+		fmt.Fprintf(os.Stderr, "  %-22s %s\n", cmd.Name+" "+cmd.Args, cmd.Summary)
+	}
+
+	fmt.Fprintf(os.Stderr, "\nRun \"%s <command> --help\" for flags of command.\n", os.Args[0])
+}
+
+// Execute parses arguments and runs command, events are received by "run" if no command was specified.
+func Execute(args []string) error {
+
+	name := "run"
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		name = args[0]
+		args = args[1:]
+	}
+
+	if name == "help" {
+		usage()
+		return nil
+	}
+
+	cmd := getCommand(name)
+	if cmd == nil {
+		usage()
+		return fmt.Errorf("Unknown command: %s", name)
+	}
+
+	fs := pflag.NewFlagSet(cmd.Name, pflag.ContinueOnError)
+	fs.String("config", "", "path of configuration file (default \"./config.toml\" or \"./configs/config.toml\")")
+	if cmd.Flags != nil {
+		cmd.Flags(fs)
+	}
+
+	fs.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s %s %s [flags]\n\n%s\n\nFlags:\n%s", os.Args[0], cmd.Name, cmd.Args, cmd.Summary, fs.FlagUsages())
+	}
+
+	err := fs.Parse(args)
+	if err == pflag.ErrHelp {
+		return nil
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if fs.NArg() != cmd.NArgs {
+		fs.Usage()
+		return fmt.Errorf("%s: %d argument(s) required", cmd.Name, cmd.NArgs)
+	}
+
+	if cmd.Name != "version" {
+		configFile, _ := fs.GetString("config")
+		err = loadConfig(configFile)
+		if err != nil {
+			return err
+		}
+
+		// Problems of logging are reported by check command as well as others
+		options, err := logging.LoadOptions()
+		if err == nil {
+			logging.Init(options)
+		} else if cmd.Name != "check" {
+			return err
+		}
+	}
+
+	return cmd.Run(fs)
+}
+
+func runCommand(fs *pflag.FlagSet) error {
+
+	// Initializing application
+	a := app.NewAppInstance()
+
+	err := a.Init()
+	if err != nil {
+		return err
+	}
+
+	// Starting application
+	return a.Run()
+}
+
+func checkCommand(fs *pflag.FlagSet) error {
+
+	a := app.NewAppInstance()
+
+	err := a.Check()
+	if err == nil {
+		fmt.Println("Configuration and database are ready")
+		return nil
+	}
+
+	if problems, ok := err.(config.Problems); ok {
+		for _, problem := range problems {
+			fmt.Println(problem)
+		}
+	} else {
+		fmt.Println(err)
+	}
+
+	os.Exit(1)
+	return nil
+}
+
+func truncateCommand(fs *pflag.FlagSet) error {
+
+	table := fs.Arg(0)
+
+	yes, _ := fs.GetBool("yes")
+	if !yes {
+		return fmt.Errorf("Truncating table \"%s\" cannot be undone, run with --yes to confirm", table)
+	}
+
+	a := app.NewAppInstance()
+	err := a.Truncate(table)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Table \"%s\" was truncated\n", table)
+
+	return nil
+}
+
+func stateCommand(fs *pflag.FlagSet) error {
+
+	a := app.NewAppInstance()
+
+	switch fs.Arg(0) {
+	case "show":
+		state, err := a.ShowState()
+		if err != nil {
+			return err
+		}
+
+		data, err := json.MarshalIndent(state, "", "  ")
+		if err != nil {
+			return err
+		}
+
+		fmt.Println(string(data))
+	case "reset":
+		pipelines, _ := fs.GetUintSlice("pipeline")
+		sequence, _ := fs.GetUint64("sequence")
+		initialLoad, _ := fs.GetBool("initial-load")
+
+		options := &subscriber.ResetOptions{
+			Pipelines:   make([]uint64, 0, len(pipelines)),
+			Sequence:    sequence,
+			InitialLoad: initialLoad,
+		}
+
+		for _, pipelineID := range pipelines {
+			options.Pipelines = append(options.Pipelines, uint64(pipelineID))
+		}
+
+		err := a.ResetState(options)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("State was reset")
+	default:
+		return fmt.Errorf("Unknown state command: %s", fs.Arg(0))
+	}
+
+	return nil
+}
+
+func replayCommand(fs *pflag.FlagSet) error {
+
+	filename := fs.Arg(0)
+	output, _ := fs.GetString("output")
+	if len(output) == 0 {
+		output = filename + ".failed"
+	}
+
+	a := app.NewAppInstance()
+	result, err := a.Replay(filename, output)
+	if err != nil {
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"replayed": result.Replayed,
+		"failed":   result.Failed,
+		"skipped":  result.Skipped,
+	}).Info("Replayed dead letters")
+
+	if result.Failed+result.Skipped > 0 {
+		fmt.Printf("%d entries were not replayed, they were written to \"%s\"\n", result.Failed+result.Skipped, output)
+	}
+
+	return nil
+}
+
+func versionCommand(fs *pflag.FlagSet) error {
+	fmt.Printf("gravity-transmitter-oracle %s (%s %s/%s)\n", Version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return nil
+}
@@ -7,8 +7,6 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
-
-	app "github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/app/instance"
 )
 
 func init() {
@@ -25,14 +23,23 @@ func init() {
 
 	log.SetLevel(debugLevel)
 
-	fmt.Printf("Debug level is set to \"%s\"\n", debugLevel.String())
+	// Outputs of commands are kept in stdout
+	fmt.Fprintf(os.Stderr, "Debug level is set to \"%s\"\n", debugLevel.String())
 
 	// From the environment
 	viper.SetEnvPrefix("GRAVITY_TRANSMITTER_ORACLE")
 	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
 	viper.AutomaticEnv()
+}
+
+func loadConfig(configFile string) error {
+
+	// From config file which was specified
+	if len(configFile) > 0 {
+		viper.SetConfigFile(configFile)
+		return viper.ReadInConfig()
+	}
 
-	// From config file
 	viper.SetConfigName("config")
 	viper.AddConfigPath("./")
 	viper.AddConfigPath("./configs")
@@ -40,23 +47,14 @@ func init() {
 	if err := viper.ReadInConfig(); err != nil {
 		log.Warn("No configuration file was loaded")
 	}
+
+	return nil
 }
 
 func main() {
 
-	// Initializing application
-	a := app.NewAppInstance()
-
-	err := a.Init()
-	if err != nil {
-		log.Fatal(err)
-		return
-	}
-
-	// Starting application
-	err = a.Run()
+	err := Execute(os.Args[1:])
 	if err != nil {
 		log.Fatal(err)
-		return
 	}
 }
@@ -3,14 +3,23 @@ module github.com/BrobridgeOrg/gravity-transmitter-oracle
 go 1.15
 
 require (
+	github.com/BrobridgeOrg/broton v0.0.7
 	github.com/BrobridgeOrg/gravity-sdk v0.0.47
 	github.com/cfsghost/buffered-input v0.0.1
+	github.com/fsnotify/fsnotify v1.4.9
 	github.com/jinzhu/copier v0.3.2
 	github.com/jmoiron/sqlx v1.3.4
-	github.com/lib/pq v1.9.0
+	github.com/lib/pq v1.10.1
 	github.com/mattn/go-oci8 v0.1.1
+	github.com/prometheus/client_golang v1.11.0
+	github.com/sijms/go-ora/v2 v2.4.20
 	github.com/sirupsen/logrus v1.8.1
+	github.com/spf13/pflag v1.0.3
 	github.com/spf13/viper v1.7.1
+	go.opentelemetry.io/otel v1.0.1
+	go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc v1.0.1
+	go.opentelemetry.io/otel/sdk v1.0.1
+	go.opentelemetry.io/otel/trace v1.0.1
 	golang.org/x/crypto v0.0.0-20210813211128-0a44fdfbc16e // indirect
 	golang.org/x/net v0.0.0-20210226172049-e18ecbb05110
 )
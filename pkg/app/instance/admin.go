@@ -0,0 +1,116 @@
+package instance
+
+import (
+	"os"
+
+	writer "github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/writer"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/state"
+	subscriber "github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/subscriber/service"
+)
+
+// openWriter connects to database without receiving and writing events.
+func (a *AppInstance) openWriter() (*writer.Writer, error) {
+
+	config, err := writer.LoadConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	w := writer.NewWriter()
+	err = w.Open(config)
+	if err != nil {
+		w.Close()
+		return nil, err
+	}
+
+	return w, nil
+}
+
+// openStateBackend opens state backend, local backend is locked by transmitter while running.
+func (a *AppInstance) openStateBackend() (state.Backend, func(), error) {
+
+	options := subscriber.LoadStateOptions()
+	err := options.Validate()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Empty store should not be created by mistake
+	if options.Backend == subscriber.StateBackendLocal {
+		_, err = os.Stat(options.Path)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		backend, err := subscriber.NewStateBackend(options, nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return backend, func() { backend.Close() }, nil
+	}
+
+	w, err := a.openWriter()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	backend, err := subscriber.NewStateBackend(options, w)
+	if err != nil {
+		w.Close()
+		return nil, nil, err
+	}
+
+	return backend, func() {
+		backend.Close()
+		w.Close()
+	}, nil
+}
+
+func (a *AppInstance) Truncate(table string) error {
+
+	w, err := a.openWriter()
+	if err != nil {
+		return err
+	}
+
+	defer w.Close()
+
+	return w.Truncate(table)
+}
+
+func (a *AppInstance) Replay(filename string, output string) (*writer.ReplayResult, error) {
+
+	w, err := a.openWriter()
+	if err != nil {
+		return nil, err
+	}
+
+	defer w.Close()
+
+	return w.Replay(filename, output)
+}
+
+func (a *AppInstance) ShowState() (*subscriber.State, error) {
+
+	backend, close, err := a.openStateBackend()
+	if err != nil {
+		return nil, err
+	}
+
+	defer close()
+
+	return subscriber.ShowState(backend)
+}
+
+func (a *AppInstance) ResetState(options *subscriber.ResetOptions) error {
+
+	backend, close, err := a.openStateBackend()
+	if err != nil {
+		return err
+	}
+
+	defer close()
+
+	return subscriber.ResetState(backend, options)
+}
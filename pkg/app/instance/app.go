@@ -1,13 +1,26 @@
 package instance
 
 import (
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+
 	writer "github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/writer"
 	subscriber "github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/subscriber/service"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/tracing"
 	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
 )
 
 type AppInstance struct {
 	done       chan bool
+	exitOnce   sync.Once
+	exitErr    error
+	role       string
+	stopTracer func()
+	roleMutex  sync.RWMutex
+	elector    *Elector
 	writer     *writer.Writer
 	subscriber *subscriber.Subscriber
 }
@@ -16,6 +29,7 @@ func NewAppInstance() *AppInstance {
 
 	a := &AppInstance{
 		done: make(chan bool),
+		role: RoleStandalone,
 	}
 
 	return a
@@ -25,35 +39,140 @@ func (a *AppInstance) Init() error {
 
 	log.Info("Starting application")
 
+	leaderOptions := LoadLeaderOptions()
+	err := leaderOptions.Validate(subscriber.LoadStateOptions())
+	if err != nil {
+		return err
+	}
+
+	if leaderOptions.Enabled {
+		a.setRole(RoleStandby)
+	}
+
+	a.stopTracer, err = tracing.Init(tracing.LoadOptions())
+	if err != nil {
+		return err
+	}
+
 	// Initializing modules
 	a.writer = writer.NewWriter()
 	a.subscriber = subscriber.NewSubscriber(a)
 
+	err = a.initHTTPServer()
+	if err != nil {
+		return err
+	}
+
 	// Initializing Writer
-	err := a.initWriter()
+	err = a.initWriter()
 	if err != nil {
 		return err
 	}
 
-	err = a.subscriber.Init()
+	if !leaderOptions.Enabled {
+		return nil
+	}
+
+	// Leader lease is shared by transmitters of the same subscriber
+	store, err := a.writer.NewLeaseStore(leaderOptions.Table, viper.GetString("subscriber.subscriberID"))
 	if err != nil {
 		return err
 	}
 
+	a.elector = NewElector(a, store, leaderOptions)
+
 	return nil
 }
 
 func (a *AppInstance) Uninit() {
+
+	log.Info("Shutting down application")
+
+	// Events are no longer received, and those being written are acknowledged before states were saved
+	a.subscriber.Stop()
+	a.writer.Close()
+	a.subscriber.Close()
+
+	// Standby takes over after states were saved
+	if a.elector != nil {
+		a.elector.Close()
+	}
+
+	// Spans which were not exported yet
+	a.stopTracer()
+}
+
+func (a *AppInstance) start() error {
+
+	// Standby doesn't subscribe to anything until leader was gone
+	if a.elector != nil {
+		if !a.elector.Campaign() {
+			return nil
+		}
+
+		a.setRole(RoleLeader)
+
+		// Transactions are committed only if this instance is still the leader
+		options := a.elector.options
+		a.writer.SetFence(options.Table, viper.GetString("subscriber.subscriberID"), leaderResource, options.InstanceID)
+	}
+
+	err := a.writer.Start()
+	if err != nil {
+		return err
+	}
+
+	err = a.subscriber.Init()
+	if err != nil {
+		return err
+	}
+
+	return a.subscriber.Run()
 }
 
 func (a *AppInstance) Run() error {
 
-	err := a.subscriber.Run()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+
+	go func() {
+		s := <-sig
+		log.WithFields(log.Fields{
+			"signal": s,
+		}).Info("Received signal")
+
+		a.Exit(nil)
+	}()
+
+	err := a.start()
 	if err != nil {
+		a.Uninit()
 		return err
 	}
 
 	<-a.done
 
-	return nil
+	a.Uninit()
+
+	return a.exitErr
+}
+
+func (a *AppInstance) Exit(err error) {
+	a.exitOnce.Do(func() {
+		a.exitErr = err
+		close(a.done)
+	})
+}
+
+func (a *AppInstance) setRole(role string) {
+	a.roleMutex.Lock()
+	defer a.roleMutex.Unlock()
+	a.role = role
+}
+
+// GetRole returns role of this instance in active/passive pair.
+func (a *AppInstance) GetRole() string {
+	a.roleMutex.RLock()
+	defer a.roleMutex.RUnlock()
+	return a.role
 }
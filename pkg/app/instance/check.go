@@ -0,0 +1,75 @@
+package instance
+
+import (
+	"strings"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/config"
+	writer "github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/writer"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/logging"
+	subscriber "github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/subscriber/service"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/tracing"
+)
+
+// Check validates configuration and rules, then verifies that database is able to be logged in
+// and every target table is ready. All problems are returned at once.
+func (a *AppInstance) Check() error {
+
+	var problems config.Problems
+
+	_, err := logging.LoadOptions()
+	problems.Add(err)
+
+	problems.Add(tracing.LoadOptions().Validate())
+
+	subscriberConfig, err := subscriber.LoadConfig()
+	problems.Add(err)
+
+	problems.Add(LoadLeaderOptions().Validate(subscriberConfig.State))
+
+	writerConfig, err := writer.LoadConfig()
+	problems.Add(err)
+
+	// Unable to connect to database without valid configuration
+	if err != nil {
+		return problems.Err()
+	}
+
+	w := writer.NewWriter()
+	defer w.Close()
+
+	err = w.Open(writerConfig)
+	if err != nil {
+		problems.Addf("database: %v", err)
+		return problems.Err()
+	}
+
+	// Rules are invalid
+	if subscriberConfig == nil || subscriberConfig.Rules == nil {
+		return problems.Err()
+	}
+
+	rules := subscriberConfig.Rules
+	checked := make(map[string]bool)
+	for collection, tables := range rules.Subscriptions {
+
+		columns := make([]string, 0)
+		rule := rules.GetInitialLoadRule(collection)
+		if len(rule.PrimaryKey) > 0 {
+			columns = append(columns, rule.PrimaryKey)
+		}
+
+		for _, table := range tables {
+
+			key := strings.ToUpper(table) + "/" + strings.Join(columns, ",")
+			if checked[key] {
+				continue
+			}
+
+			checked[key] = true
+
+			problems.Add(w.CheckTable(table, columns))
+		}
+	}
+
+	return problems.Err()
+}
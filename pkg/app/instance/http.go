@@ -0,0 +1,45 @@
+package instance
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+func (a *AppInstance) initHTTPServer() error {
+
+	host := viper.GetString("http.host")
+	if len(host) == 0 {
+		return nil
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", metrics.Handler())
+	mux.HandleFunc("/health", a.healthHandler)
+
+	log.WithFields(log.Fields{
+		"host": host,
+	}).Info("Starting HTTP server")
+
+	go func() {
+		err := http.ListenAndServe(host, mux)
+		if err != nil {
+			log.Error(err)
+		}
+	}()
+
+	return nil
+}
+
+func (a *AppInstance) healthHandler(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Content-Type", "application/json")
+
+	json.NewEncoder(w).Encode(map[string]string{
+		"status": "ok",
+		"role":   a.GetRole(),
+	})
+}
@@ -0,0 +1,194 @@
+package instance
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/lease"
+	subscriber "github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/subscriber/service"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	RoleStandalone = "standalone"
+	RoleStandby    = "standby"
+	RoleLeader     = "leader"
+)
+
+const leaderResource = "leader"
+
+type LeaderOptions struct {
+	Enabled    bool
+	Table      string
+	InstanceID string
+	LeaseTTL   time.Duration
+}
+
+func LoadLeaderOptions() *LeaderOptions {
+
+	hostname, _ := os.Hostname()
+
+	viper.SetDefault("ha.table", "GRAVITY_LEASE")
+	viper.SetDefault("ha.instanceID", hostname)
+	viper.SetDefault("ha.leaseTTL", 10)
+
+	return &LeaderOptions{
+		Enabled:    viper.GetBool("ha.enabled"),
+		Table:      viper.GetString("ha.table"),
+		InstanceID: viper.GetString("ha.instanceID"),
+		LeaseTTL:   viper.GetDuration("ha.leaseTTL") * time.Second,
+	}
+}
+
+func (options *LeaderOptions) Validate(stateOptions *subscriber.StateOptions) error {
+
+	if !options.Enabled {
+		return nil
+	}
+
+	if len(options.Table) == 0 {
+		return fmt.Errorf("ha.table: table is required")
+	}
+
+	if len(options.InstanceID) == 0 {
+		return fmt.Errorf("ha.instanceID: instance ID is required")
+	}
+
+	if options.LeaseTTL < time.Second*3 {
+		return fmt.Errorf("ha.leaseTTL: should not be less than 3")
+	}
+
+	// Standby continues from sequences which were saved by leader
+	if stateOptions.Backend != subscriber.StateBackendOracle {
+		return fmt.Errorf("ha: oracle state backend is required")
+	}
+
+	return nil
+}
+
+// Elector holds leader lease, only the leader receives events and writes to database.
+type Elector struct {
+	app       *AppInstance
+	store     lease.Store
+	options   *LeaderOptions
+	renewedAt time.Time
+	leading   bool
+	closed    chan struct{}
+	done      chan struct{}
+}
+
+func NewElector(a *AppInstance, store lease.Store, options *LeaderOptions) *Elector {
+	return &Elector{
+		app:     a,
+		store:   store,
+		options: options,
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+}
+
+func (e *Elector) acquire() (bool, error) {
+
+	startedAt := time.Now()
+
+	ok, err := e.store.Acquire(leaderResource, e.options.InstanceID, e.options.LeaseTTL)
+	if err != nil || !ok {
+		return false, err
+	}
+
+	e.renewedAt = startedAt
+
+	return true, nil
+}
+
+// Campaign blocks until this instance became leader, false is returned if application was exiting.
+func (e *Elector) Campaign() bool {
+
+	log.WithFields(log.Fields{
+		"instanceID": e.options.InstanceID,
+	}).Info("Waiting for leader lease")
+
+	ticker := time.NewTicker(e.options.LeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		ok, err := e.acquire()
+		if err != nil {
+			log.Error(err)
+		} else if ok {
+			log.WithFields(log.Fields{
+				"instanceID": e.options.InstanceID,
+			}).Info("Became leader")
+
+			e.leading = true
+			go e.run()
+
+			return true
+		}
+
+		select {
+		case <-ticker.C:
+		case <-e.app.done:
+			return false
+		}
+	}
+}
+
+func (e *Elector) run() {
+
+	defer close(e.done)
+
+	ticker := time.NewTicker(e.options.LeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			ok, err := e.acquire()
+			if err != nil {
+				log.Error(err)
+
+				// Stepping down before lease was expired, so that standby never writes at the same time
+				if time.Since(e.renewedAt) > e.options.LeaseTTL/2 {
+					e.stepDown(fmt.Errorf("Leader lease was not renewed in time: %v", err))
+					return
+				}
+
+				continue
+			}
+
+			if !ok {
+				e.stepDown(fmt.Errorf("Leader lease was taken by another transmitter"))
+				return
+			}
+		case <-e.closed:
+			return
+		}
+	}
+}
+
+// stepDown stops writing at once rather than waiting for in-flight batches, they are rolled back
+// and will be written by the new leader.
+func (e *Elector) stepDown(err error) {
+	e.app.writer.Abort()
+	e.app.Exit(err)
+}
+
+// Close releases leader lease, it should be called after states were saved so that
+// standby continues from the last sequence.
+func (e *Elector) Close() {
+
+	if e.leading {
+		close(e.closed)
+		<-e.done
+
+		err := e.store.Release(leaderResource, e.options.InstanceID)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	e.store.Close()
+}
@@ -5,6 +5,10 @@ import (
 )
 
 func (a *AppInstance) initWriter() error {
+
+	// Writer shuts down application gracefully if it cannot keep going
+	a.writer.SetExitHandler(a.Exit)
+
 	return a.writer.Init()
 }
 
@@ -6,4 +6,7 @@ import (
 
 type App interface {
 	GetWriter() database.Writer
+
+	// Exit shuts down application gracefully, and error is returned by Run
+	Exit(error)
 }
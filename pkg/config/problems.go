@@ -0,0 +1,66 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Problems collects all problems which were found in configuration, so that
+// they can be reported at once rather than one by one.
+type Problems []error
+
+// Add appends error to problems, nil is ignored.
+func (p *Problems) Add(err error) {
+
+	if err == nil {
+		return
+	}
+
+	if more, ok := err.(Problems); ok {
+		*p = append(*p, more...)
+		return
+	}
+
+	*p = append(*p, err)
+}
+
+// AddWithPrefix is the same as Add, but every problem is prefixed with where it was found.
+func (p *Problems) AddWithPrefix(prefix string, err error) {
+
+	if err == nil {
+		return
+	}
+
+	more, ok := err.(Problems)
+	if !ok {
+		more = Problems{err}
+	}
+
+	for _, e := range more {
+		p.Addf("%s: %v", prefix, e)
+	}
+}
+
+func (p *Problems) Addf(format string, args ...interface{}) {
+	*p = append(*p, fmt.Errorf(format, args...))
+}
+
+// Err returns nil if no problem was found.
+func (p Problems) Err() error {
+
+	if len(p) == 0 {
+		return nil
+	}
+
+	return p
+}
+
+func (p Problems) Error() string {
+
+	messages := make([]string, 0, len(p))
+	for _, err := range p {
+		messages = append(messages, err.Error())
+	}
+
+	return strings.Join(messages, "\n")
+}
@@ -0,0 +1,347 @@
+package connstr
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const DefaultPort = 1521
+
+type Address struct {
+	Host string
+	Port int
+}
+
+// Options describes how to connect to database. ConnectString is used as it is if it was set,
+// otherwise connect descriptor is built by hosts or the single host.
+type Options struct {
+	Username string
+	Password string
+	Param    string
+
+	// TNS alias, EZConnect string or full connect descriptor
+	ConnectString string
+
+	// Single host, it is the shorthand of connect string
+	Host string
+	Port int
+
+	// Multiple hosts for RAC or Data Guard, in form of "host:port"
+	Hosts       []string
+	LoadBalance bool
+	Failover    bool
+
+	ServiceName string
+	SID         string
+
+	// Unit: second
+	ConnectTimeout int
+	RetryCount     int
+	RetryDelay     int
+
+	// TCPS
+	TLS           bool
+	ServerDNMatch bool
+	ServerCertDN  string
+
+	// Wallet is required by TCPS and external authentication
+	WalletDir    string
+	ExternalAuth bool
+}
+
+// Files which are one of wallet
+var walletFiles = []string{
+	"cwallet.sso",
+	"ewallet.p12",
+}
+
+// ParseAddress parses address in form of "host:port", port is optional.
+func ParseAddress(addr string, defaultPort int) (*Address, error) {
+
+	addr = strings.TrimSpace(addr)
+	if len(addr) == 0 {
+		return nil, errors.New("Empty address")
+	}
+
+	host, portStr, err := net.SplitHostPort(addr)
+	if err != nil {
+		// No port specified
+		if strings.Contains(err.Error(), "missing port") {
+			return &Address{
+				Host: strings.Trim(addr, "[]"),
+				Port: defaultPort,
+			}, nil
+		}
+
+		return nil, err
+	}
+
+	port, err := strconv.Atoi(portStr)
+	if err != nil || port <= 0 || port > 65535 {
+		return nil, fmt.Errorf("Invalid port: %s", addr)
+	}
+
+	return &Address{
+		Host: host,
+		Port: port,
+	}, nil
+}
+
+func (options *Options) Validate() error {
+
+	if len(options.ServiceName) > 0 && len(options.SID) > 0 {
+		return errors.New("Only one of serviceName or sid can be used")
+	}
+
+	if len(options.ConnectString) > 0 && len(options.Hosts) > 0 {
+		return errors.New("Only one of connectString or hosts can be used")
+	}
+
+	if options.ExternalAuth {
+		if len(options.Username) > 0 || len(options.Password) > 0 {
+			return errors.New("username and password should be empty for external authentication")
+		}
+
+		if len(options.WalletDir) == 0 {
+			return errors.New("Wallet directory is required by external authentication")
+		}
+	}
+
+	if options.TLS && len(options.WalletDir) == 0 {
+		return errors.New("Wallet directory is required by TCPS")
+	}
+
+	if len(options.ConnectString) > 0 {
+
+		// TCPS should be configured in descriptor
+		if options.TLS && strings.HasPrefix(options.ConnectString, "(") && !strings.Contains(strings.ToUpper(options.ConnectString), "TCPS") {
+			return errors.New("PROTOCOL=TCPS is required in connectString for TCPS")
+		}
+
+		return nil
+	}
+
+	if len(options.Hosts) == 0 && len(options.Host) == 0 {
+		return errors.New("No host specified")
+	}
+
+	if options.ConnectTimeout < 0 || options.RetryCount < 0 || options.RetryDelay < 0 {
+		return errors.New("connectTimeout, retryCount and retryDelay should not be less than 0")
+	}
+
+	return nil
+}
+
+func (options *Options) getAddresses() ([]*Address, error) {
+
+	if len(options.Hosts) == 0 {
+		port := options.Port
+		if port == 0 {
+			port = DefaultPort
+		}
+
+		return []*Address{
+			&Address{
+				Host: options.Host,
+				Port: port,
+			},
+		}, nil
+	}
+
+	addresses := make([]*Address, 0, len(options.Hosts))
+	for _, host := range options.Hosts {
+		addr, err := ParseAddress(host, DefaultPort)
+		if err != nil {
+			return nil, err
+		}
+
+		addresses = append(addresses, addr)
+	}
+
+	return addresses, nil
+}
+
+func onOff(enabled bool) string {
+
+	if enabled {
+		return "on"
+	}
+
+	return "off"
+}
+
+// Descriptor returns connect descriptor which is built by options.
+func (options *Options) Descriptor() (string, error) {
+
+	if len(options.ServiceName) == 0 && len(options.SID) == 0 {
+		return "", errors.New("serviceName or sid is required")
+	}
+
+	addresses, err := options.getAddresses()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	b.WriteString("(DESCRIPTION=")
+
+	if options.ConnectTimeout > 0 {
+		fmt.Fprintf(&b, "(CONNECT_TIMEOUT=%d)", options.ConnectTimeout)
+	}
+
+	if options.RetryCount > 0 {
+		fmt.Fprintf(&b, "(RETRY_COUNT=%d)", options.RetryCount)
+	}
+
+	if options.RetryDelay > 0 {
+		fmt.Fprintf(&b, "(RETRY_DELAY=%d)", options.RetryDelay)
+	}
+
+	b.WriteString("(ADDRESS_LIST=")
+
+	if len(addresses) > 1 {
+		fmt.Fprintf(&b, "(LOAD_BALANCE=%s)(FAILOVER=%s)", onOff(options.LoadBalance), onOff(options.Failover))
+	}
+
+	protocol := "TCP"
+	if options.TLS {
+		protocol = "TCPS"
+	}
+
+	for _, addr := range addresses {
+		fmt.Fprintf(&b, "(ADDRESS=(PROTOCOL=%s)(HOST=%s)(PORT=%d))", protocol, addr.Host, addr.Port)
+	}
+
+	b.WriteString(")(CONNECT_DATA=")
+
+	if len(options.ServiceName) > 0 {
+		fmt.Fprintf(&b, "(SERVICE_NAME=%s)", options.ServiceName)
+	} else {
+		fmt.Fprintf(&b, "(SID=%s)", options.SID)
+	}
+
+	b.WriteString(")")
+
+	if options.TLS {
+		fmt.Fprintf(&b, "(SECURITY=(SSL_SERVER_DN_MATCH=%s)", onOff(options.ServerDNMatch))
+
+		if len(options.ServerCertDN) > 0 {
+			fmt.Fprintf(&b, "(SSL_SERVER_CERT_DN=\"%s\")", options.ServerCertDN)
+		}
+
+		fmt.Fprintf(&b, "(MY_WALLET_DIRECTORY=%s))", options.WalletDir)
+	}
+
+	b.WriteString(")")
+
+	return b.String(), nil
+}
+
+// GetConnectString returns connect string without credential.
+func (options *Options) GetConnectString() (string, error) {
+
+	err := options.Validate()
+	if err != nil {
+		return "", err
+	}
+
+	if len(options.ConnectString) > 0 {
+		return options.ConnectString, nil
+	}
+
+	// Descriptor is required for multiple hosts, timeouts and TCPS
+	if len(options.Hosts) > 0 || options.ConnectTimeout > 0 || options.RetryCount > 0 || options.RetryDelay > 0 || options.TLS {
+		return options.Descriptor()
+	}
+
+	port := options.Port
+	if port == 0 {
+		port = DefaultPort
+	}
+
+	dbname := options.ServiceName
+	if len(options.SID) > 0 {
+		dbname = options.SID
+	}
+
+	return fmt.Sprintf("%s:%d/%s", options.Host, port, dbname), nil
+}
+
+// Build returns DSN in form of "username/password@connect_string?param".
+func (options *Options) Build() (string, error) {
+
+	connectString, err := options.GetConnectString()
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+
+	// Credential is provided by wallet for external authentication
+	if !options.ExternalAuth && len(options.Username) > 0 {
+		b.WriteString(Escape(options.Username))
+
+		if len(options.Password) > 0 {
+			b.WriteString("/")
+			b.WriteString(Escape(options.Password))
+		}
+
+		b.WriteString("@")
+	}
+
+	b.WriteString(strings.ReplaceAll(connectString, "%", "%25"))
+
+	if len(options.Param) > 0 {
+		b.WriteString("?")
+		b.WriteString(options.Param)
+	}
+
+	return b.String(), nil
+}
+
+// ValidateWallet checks whether wallet can be found in directory.
+func ValidateWallet(dir string) error {
+
+	info, err := os.Stat(dir)
+	if err != nil {
+		return fmt.Errorf("Unable to access wallet directory: %v", err)
+	}
+
+	if !info.IsDir() {
+		return fmt.Errorf("Wallet directory is not a directory: %s", dir)
+	}
+
+	for _, filename := range walletFiles {
+		_, err := os.Stat(filepath.Join(dir, filename))
+		if err == nil {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("No wallet (%s) found in %s", strings.Join(walletFiles, " or "), dir)
+}
+
+// Escape encodes characters which have special meaning in DSN.
+func Escape(str string) string {
+
+	var b strings.Builder
+	for i := 0; i < len(str); i++ {
+		c := str[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z', '0' <= c && c <= '9':
+			b.WriteByte(c)
+		case c == '-' || c == '_' || c == '.' || c == '~' || c == '$' || c == '#':
+			b.WriteByte(c)
+		default:
+			fmt.Fprintf(&b, "%%%02X", c)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,294 @@
+package connstr
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseAddress(t *testing.T) {
+
+	tests := []struct {
+		addr string
+		host string
+		port int
+	}{
+		{"db1", "db1", DefaultPort},
+		{"db1:1522", "db1", 1522},
+		{"192.168.1.111:1521", "192.168.1.111", 1521},
+		{" db2 ", "db2", DefaultPort},
+		{"[fe80::1]:1523", "fe80::1", 1523},
+	}
+
+	for _, test := range tests {
+		addr, err := ParseAddress(test.addr, DefaultPort)
+		if err != nil {
+			t.Errorf("%q: %v", test.addr, err)
+			continue
+		}
+
+		if addr.Host != test.host || addr.Port != test.port {
+			t.Errorf("%q: expected %s:%d, got %s:%d", test.addr, test.host, test.port, addr.Host, addr.Port)
+		}
+	}
+
+	for _, addr := range []string{"", "db1:abc", "db1:0", "db1:70000"} {
+		_, err := ParseAddress(addr, DefaultPort)
+		if err == nil {
+			t.Errorf("%q: error is expected", addr)
+		}
+	}
+}
+
+func TestShorthand(t *testing.T) {
+
+	options := &Options{
+		Username:    "gravity",
+		Password:    "gravity",
+		Host:        "192.168.1.111",
+		Port:        1521,
+		ServiceName: "orcl",
+		Param:       "loc=UTC",
+	}
+
+	dsn, err := options.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "gravity/gravity@192.168.1.111:1521/orcl?loc=UTC"
+	if dsn != expected {
+		t.Errorf("expected %s, got %s", expected, dsn)
+	}
+}
+
+func TestShorthandDefaultPort(t *testing.T) {
+
+	options := &Options{
+		Host: "db1",
+		SID:  "ORCL",
+	}
+
+	connectString, err := options.GetConnectString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if connectString != "db1:1521/ORCL" {
+		t.Errorf("unexpected connect string: %s", connectString)
+	}
+}
+
+func TestConnectString(t *testing.T) {
+
+	tests := []string{
+		"ORCL_TNS",
+		"db1:1521/orcl",
+		"(DESCRIPTION=(ADDRESS=(PROTOCOL=TCP)(HOST=db1)(PORT=1521))(CONNECT_DATA=(SERVICE_NAME=orcl)))",
+	}
+
+	for _, test := range tests {
+		options := &Options{
+			Username:      "gravity",
+			Password:      "gravity",
+			ConnectString: test,
+
+			// Ignored if connect string was set
+			Host: "192.168.1.111",
+		}
+
+		dsn, err := options.Build()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if dsn != "gravity/gravity@"+test {
+			t.Errorf("unexpected DSN: %s", dsn)
+		}
+	}
+}
+
+func TestHosts(t *testing.T) {
+
+	options := &Options{
+		Hosts:          []string{"rac1:1521", "rac2"},
+		LoadBalance:    true,
+		Failover:       true,
+		ServiceName:    "orcl",
+		ConnectTimeout: 10,
+		RetryCount:     3,
+		RetryDelay:     1,
+	}
+
+	connectString, err := options.GetConnectString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "(DESCRIPTION=(CONNECT_TIMEOUT=10)(RETRY_COUNT=3)(RETRY_DELAY=1)" +
+		"(ADDRESS_LIST=(LOAD_BALANCE=on)(FAILOVER=on)" +
+		"(ADDRESS=(PROTOCOL=TCP)(HOST=rac1)(PORT=1521))" +
+		"(ADDRESS=(PROTOCOL=TCP)(HOST=rac2)(PORT=1521)))" +
+		"(CONNECT_DATA=(SERVICE_NAME=orcl)))"
+
+	if connectString != expected {
+		t.Errorf("expected %s, got %s", expected, connectString)
+	}
+}
+
+func TestDescriptorSingleHost(t *testing.T) {
+
+	options := &Options{
+		Host:           "db1",
+		Port:           1522,
+		SID:            "ORCL",
+		ConnectTimeout: 5,
+	}
+
+	connectString, err := options.GetConnectString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "(DESCRIPTION=(CONNECT_TIMEOUT=5)(ADDRESS_LIST=(ADDRESS=(PROTOCOL=TCP)(HOST=db1)(PORT=1522)))(CONNECT_DATA=(SID=ORCL)))"
+	if connectString != expected {
+		t.Errorf("expected %s, got %s", expected, connectString)
+	}
+}
+
+func TestInvalidOptions(t *testing.T) {
+
+	tests := []*Options{
+		&Options{Host: "db1", ServiceName: "orcl", SID: "ORCL"},
+		&Options{ConnectString: "ORCL_TNS", Hosts: []string{"db1"}},
+		&Options{ServiceName: "orcl"},
+		&Options{Hosts: []string{"db1"}},
+		&Options{Hosts: []string{"db1:abc"}, ServiceName: "orcl"},
+		&Options{Host: "db1", ServiceName: "orcl", ConnectTimeout: -1},
+	}
+
+	for i, options := range tests {
+		_, err := options.Build()
+		if err == nil {
+			t.Errorf("case %d: error is expected", i)
+		}
+	}
+}
+
+func TestEscape(t *testing.T) {
+
+	options := &Options{
+		Username:      "gravity",
+		Password:      "p@ss/w:rd%?",
+		ConnectString: "ORCL_TNS",
+	}
+
+	dsn, err := options.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "gravity/p%40ss%2Fw%3Ard%25%3F@ORCL_TNS"
+	if dsn != expected {
+		t.Errorf("expected %s, got %s", expected, dsn)
+	}
+}
+
+func TestTLS(t *testing.T) {
+
+	options := &Options{
+		Username:      "gravity",
+		Password:      "gravity",
+		Host:          "adb.ap-tokyo-1.oraclecloud.com",
+		Port:          1522,
+		ServiceName:   "gravity_high.adb.oraclecloud.com",
+		TLS:           true,
+		ServerDNMatch: true,
+		ServerCertDN:  "CN=adwc.uscom-east-1.oraclecloud.com, O=Oracle Corporation, C=US",
+		WalletDir:     "/opt/wallet",
+	}
+
+	connectString, err := options.GetConnectString()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "(DESCRIPTION=(ADDRESS_LIST=(ADDRESS=(PROTOCOL=TCPS)(HOST=adb.ap-tokyo-1.oraclecloud.com)(PORT=1522)))" +
+		"(CONNECT_DATA=(SERVICE_NAME=gravity_high.adb.oraclecloud.com))" +
+		"(SECURITY=(SSL_SERVER_DN_MATCH=on)(SSL_SERVER_CERT_DN=\"CN=adwc.uscom-east-1.oraclecloud.com, O=Oracle Corporation, C=US\")(MY_WALLET_DIRECTORY=/opt/wallet)))"
+
+	if connectString != expected {
+		t.Errorf("expected %s, got %s", expected, connectString)
+	}
+}
+
+func TestExternalAuth(t *testing.T) {
+
+	options := &Options{
+		ConnectString: "gravity_high",
+		WalletDir:     "/opt/wallet",
+		ExternalAuth:  true,
+	}
+
+	dsn, err := options.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if dsn != "gravity_high" {
+		t.Errorf("unexpected DSN: %s", dsn)
+	}
+}
+
+func TestInvalidSecurityOptions(t *testing.T) {
+
+	tests := []*Options{
+		&Options{ConnectString: "gravity_high", ExternalAuth: true},
+		&Options{ConnectString: "gravity_high", ExternalAuth: true, WalletDir: "/opt/wallet", Username: "gravity"},
+		&Options{Host: "db1", ServiceName: "orcl", TLS: true},
+		&Options{ConnectString: "(DESCRIPTION=(ADDRESS=(PROTOCOL=TCP)(HOST=db1)(PORT=1521))(CONNECT_DATA=(SERVICE_NAME=orcl)))", TLS: true, WalletDir: "/opt/wallet"},
+	}
+
+	for i, options := range tests {
+		_, err := options.Build()
+		if err == nil {
+			t.Errorf("case %d: error is expected", i)
+		}
+	}
+}
+
+func TestValidateWallet(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "wallet")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer os.RemoveAll(dir)
+
+	err = ValidateWallet(dir)
+	if err == nil {
+		t.Error("error is expected for empty directory")
+	}
+
+	err = ValidateWallet(filepath.Join(dir, "notfound"))
+	if err == nil {
+		t.Error("error is expected for directory which does not exist")
+	}
+
+	err = ioutil.WriteFile(filepath.Join(dir, "cwallet.sso"), []byte{}, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	err = ValidateWallet(dir)
+	if err != nil {
+		t.Error(err)
+	}
+
+	err = ValidateWallet(filepath.Join(dir, "cwallet.sso"))
+	if err == nil {
+		t.Error("error is expected for file")
+	}
+}
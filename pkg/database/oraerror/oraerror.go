@@ -0,0 +1,166 @@
+package oraerror
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"regexp"
+	"strconv"
+)
+
+type Category string
+
+const (
+	CategoryUnknown       Category = "unknown"
+	CategoryConnectivity  Category = "connectivity"
+	CategoryConstraint    Category = "constraint"
+	CategoryDataTooLarge  Category = "data_too_large"
+	CategoryMissingObject Category = "missing_object"
+	CategoryDeadlock      Category = "deadlock"
+	CategoryPermission    Category = "permission"
+	CategoryTimeout       Category = "timeout"
+)
+
+var codePattern = regexp.MustCompile(`ORA-(\d{5})`)
+
+var categories = map[int]Category{
+
+	// Connectivity
+	1012:  CategoryConnectivity, // not logged on
+	1033:  CategoryConnectivity, // initialization or shutdown in progress
+	1034:  CategoryConnectivity, // ORACLE not available
+	1089:  CategoryConnectivity, // immediate shutdown in progress
+	1092:  CategoryConnectivity, // ORACLE instance terminated
+	2396:  CategoryConnectivity, // exceeded maximum idle time
+	3113:  CategoryConnectivity, // end-of-file on communication channel
+	3114:  CategoryConnectivity, // not connected to ORACLE
+	3135:  CategoryConnectivity, // connection lost contact
+	12153: CategoryConnectivity, // not connected
+	12170: CategoryConnectivity, // connect timeout occurred
+	12514: CategoryConnectivity, // listener does not currently know of service
+	12528: CategoryConnectivity, // all appropriate instances are blocking new connections
+	12537: CategoryConnectivity, // connection closed
+	12541: CategoryConnectivity, // no listener
+	12543: CategoryConnectivity, // destination host unreachable
+	12545: CategoryConnectivity, // target host or object does not exist
+	12547: CategoryConnectivity, // lost contact
+	12560: CategoryConnectivity, // protocol adapter error
+	12571: CategoryConnectivity, // packet writer failure
+	25408: CategoryConnectivity, // can not safely replay call
+
+	// Constraint violation
+	1:    CategoryConstraint, // unique constraint violated
+	1400: CategoryConstraint, // cannot insert NULL
+	1407: CategoryConstraint, // cannot update to NULL
+	2290: CategoryConstraint, // check constraint violated
+	2291: CategoryConstraint, // parent key not found
+	2292: CategoryConstraint, // child record found
+
+	// Data too large
+	1401:  CategoryDataTooLarge, // inserted value too large for column
+	1438:  CategoryDataTooLarge, // value larger than specified precision
+	12899: CategoryDataTooLarge, // value too large for column
+	22835: CategoryDataTooLarge, // buffer too small for CLOB to CHAR or BLOB to RAW conversion
+
+	// Missing object
+	904:  CategoryMissingObject, // invalid identifier
+	942:  CategoryMissingObject, // table or view does not exist
+	980:  CategoryMissingObject, // synonym translation is no longer valid
+	2289: CategoryMissingObject, // sequence does not exist
+	4043: CategoryMissingObject, // object does not exist
+
+	// Deadlock
+	60:   CategoryDeadlock, // deadlock detected while waiting for resource
+	4020: CategoryDeadlock, // deadlock detected while trying to lock object
+
+	// Permission
+	1017:  CategoryPermission, // invalid username/password
+	1031:  CategoryPermission, // insufficient privileges
+	1045:  CategoryPermission, // lacks CREATE SESSION privilege
+	1950:  CategoryPermission, // no privileges on tablespace
+	28000: CategoryPermission, // account is locked
+	28001: CategoryPermission, // password has expired
+
+	// Timeout
+	54:    CategoryTimeout, // resource busy and acquire with NOWAIT specified or timeout expired
+	1013:  CategoryTimeout, // user requested cancel of current operation
+	30006: CategoryTimeout, // resource busy; acquire with WAIT timeout expired
+}
+
+// Error is an error returned by database with ORA code and category.
+type Error struct {
+	Code     int
+	Category Category
+	Err      error
+}
+
+func (e *Error) Error() string {
+	return e.Err.Error()
+}
+
+func (e *Error) Unwrap() error {
+	return e.Err
+}
+
+// Parse parses ORA code from error which is returned by driver.
+func Parse(err error) *Error {
+
+	if err == nil {
+		return nil
+	}
+
+	var e *Error
+	if errors.As(err, &e) {
+		return e
+	}
+
+	e = &Error{
+		Category: CategoryUnknown,
+		Err:      err,
+	}
+
+	if errors.Is(err, driver.ErrBadConn) {
+		e.Category = CategoryConnectivity
+		return e
+	}
+
+	// Statement or transaction was canceled by context
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		e.Category = CategoryTimeout
+		return e
+	}
+
+	matches := codePattern.FindStringSubmatch(err.Error())
+	if len(matches) < 2 {
+		return e
+	}
+
+	code, _ := strconv.Atoi(matches[1])
+	e.Code = code
+
+	if category, ok := categories[code]; ok {
+		e.Category = category
+	}
+
+	return e
+}
+
+// GetCategory returns category of error.
+func GetCategory(err error) Category {
+
+	if err == nil {
+		return CategoryUnknown
+	}
+
+	return Parse(err).Category
+}
+
+// IsPermanent returns true if error won't be fixed by retrying the same statement.
+func (category Category) IsPermanent() bool {
+	switch category {
+	case CategoryConstraint, CategoryDataTooLarge:
+		return true
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package oraerror
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+
+	tests := []struct {
+		err      error
+		code     int
+		category Category
+	}{
+		{errors.New("ORA-00001: unique constraint (GRAVITY.PK_USERS) violated"), 1, CategoryConstraint},
+		{errors.New("ORA-01400: cannot insert NULL into (\"GRAVITY\".\"USERS\".\"ID\")"), 1400, CategoryConstraint},
+		{errors.New("ORA-12899: value too large for column \"GRAVITY\".\"USERS\".\"NAME\" (actual: 40, maximum: 20)"), 12899, CategoryDataTooLarge},
+		{errors.New("ORA-00942: table or view does not exist"), 942, CategoryMissingObject},
+		{errors.New("ORA-00060: deadlock detected while waiting for resource"), 60, CategoryDeadlock},
+		{errors.New("ORA-01031: insufficient privileges"), 1031, CategoryPermission},
+		{errors.New("ORA-03113: end-of-file on communication channel"), 3113, CategoryConnectivity},
+		{errors.New("ORA-20001: raised by application"), 20001, CategoryUnknown},
+		{errors.New("connection refused"), 0, CategoryUnknown},
+		{driver.ErrBadConn, 0, CategoryConnectivity},
+		{fmt.Errorf("exec failed: %w", errors.New("ORA-12541: TNS:no listener")), 12541, CategoryConnectivity},
+		{errors.New("ORA-01013: user requested cancel of current operation"), 1013, CategoryTimeout},
+		{errors.New("ORA-30006: resource busy; acquire with WAIT timeout expired"), 30006, CategoryTimeout},
+		{context.DeadlineExceeded, 0, CategoryTimeout},
+		{fmt.Errorf("batch failed: %w", context.Canceled), 0, CategoryTimeout},
+	}
+
+	for _, test := range tests {
+		e := Parse(test.err)
+		if e.Code != test.code {
+			t.Errorf("%q: expected code %d, got %d", test.err, test.code, e.Code)
+		}
+
+		if e.Category != test.category {
+			t.Errorf("%q: expected category %s, got %s", test.err, test.category, e.Category)
+		}
+
+		if !errors.Is(e, test.err) {
+			t.Errorf("%q: original error should be wrapped", test.err)
+		}
+	}
+}
+
+func TestParseNil(t *testing.T) {
+
+	if Parse(nil) != nil {
+		t.Error("nil should be returned for nil error")
+	}
+
+	if GetCategory(nil) != CategoryUnknown {
+		t.Error("category of nil error should be unknown")
+	}
+}
+
+func TestParseTwice(t *testing.T) {
+
+	e := Parse(errors.New("ORA-00060: deadlock detected while waiting for resource"))
+	wrapped := fmt.Errorf("batch failed: %w", e)
+
+	if Parse(wrapped) != e {
+		t.Error("parsed error should be reused")
+	}
+}
+
+func TestIsPermanent(t *testing.T) {
+
+	permanent := map[Category]bool{
+		CategoryUnknown:       false,
+		CategoryConnectivity:  false,
+		CategoryConstraint:    true,
+		CategoryDataTooLarge:  true,
+		CategoryMissingObject: false,
+		CategoryDeadlock:      false,
+		CategoryPermission:    false,
+		CategoryTimeout:       false,
+	}
+
+	for category, expected := range permanent {
+		if category.IsPermanent() != expected {
+			t.Errorf("%s: expected %v", category, expected)
+		}
+	}
+}
@@ -1,7 +1,12 @@
 package database
 
 import (
+	"context"
+	"time"
+
 	gravity_sdk_types_record "github.com/BrobridgeOrg/gravity-sdk/types/record"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/lease"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/state"
 )
 
 type DBCommand interface {
@@ -11,11 +16,53 @@ type DBCommand interface {
 	GetTables() []string
 }
 
+type EventReference interface {
+	GetPipelineID() uint64
+	GetSequence() uint64
+
+	// Context carries span of event
+	GetContext() context.Context
+
+	// Time of event at source for measuring lag, it's zero if record carries no time
+	GetEventTime() time.Time
+
+	// Time of receiving event for measuring latency of transmitter
+	GetReceivedAt() time.Time
+}
+
 type CompletionHandler func(DBCommand)
 
+type BulkOptions struct {
+	Enabled        bool `json:"enabled"`
+	NoLogging      bool `json:"nologging"`
+	DisableIndexes bool `json:"disableIndexes"`
+}
+
+type ShadowMethod string
+
+const (
+	ShadowMethodSynonym ShadowMethod = "synonym"
+)
+
+// ShadowOptions for synonym which is switched between two tables, tables are <synonym>_A and <synonym>_B by default.
+type ShadowOptions struct {
+	Method ShadowMethod `json:"method"`
+	Tables []string     `json:"tables"`
+}
+
 type Writer interface {
 	Init() error
 	ProcessData(interface{}, *gravity_sdk_types_record.Record, []string) error
+	UpsertRecord(interface{}, *gravity_sdk_types_record.Record, []string) error
+	BulkInsertRecord(interface{}, *gravity_sdk_types_record.Record, []string) error
+	Reject(interface{}, *gravity_sdk_types_record.Record, []string, error)
 	SetCompletionHandler(CompletionHandler)
 	Truncate(string) error
+	GetEstimatedRowCount(string) (uint64, error)
+	PrepareShadowTable(string, *ShadowOptions, bool) (string, error)
+	SwapShadowTable(string, string, *ShadowOptions) error
+	PrepareBulkLoad(string, *BulkOptions) error
+	FinishBulkLoad(string, *BulkOptions) error
+	NewStateBackend(string, string, time.Duration) (state.Backend, error)
+	NewLeaseStore(string, string) (lease.Store, error)
 }
@@ -0,0 +1,118 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+// Limitations of DBMS_APPLICATION_INFO
+const (
+	maxModuleLength     = 48
+	maxActionLength     = 32
+	maxClientInfoLength = 64
+)
+
+var SetActionTemplate = `BEGIN DBMS_APPLICATION_INFO.SET_ACTION(:1); END;`
+
+// AppInfoOptions decides how sessions are tagged for being told apart in V$SESSION.
+type AppInfoOptions struct {
+	Enabled          bool
+	Module           string
+	ClientInfo       string
+	ClientIdentifier string
+}
+
+func LoadAppInfoOptions() *AppInfoOptions {
+
+	viper.SetDefault("database.appInfo.enabled", true)
+	viper.SetDefault("database.appInfo.module", "gravity-transmitter-oracle")
+	viper.SetDefault("database.appInfo.clientInfo", viper.GetString("subscriber.subscriberID"))
+
+	return &AppInfoOptions{
+		Enabled:          viper.GetBool("database.appInfo.enabled"),
+		Module:           truncate(viper.GetString("database.appInfo.module"), maxModuleLength),
+		ClientInfo:       truncate(viper.GetString("database.appInfo.clientInfo"), maxClientInfoLength),
+		ClientIdentifier: viper.GetString("database.appInfo.clientIdentifier"),
+	}
+}
+
+func truncate(str string, length int) string {
+
+	if len(str) <= length {
+		return str
+	}
+
+	return str[:length]
+}
+
+func quote(str string) string {
+	return "'" + strings.ReplaceAll(str, "'", "''") + "'"
+}
+
+// GetStatements returns statements to tag session.
+func (options *AppInfoOptions) GetStatements() []string {
+
+	statements := make([]string, 0)
+
+	if options.Enabled {
+		statements = append(statements,
+			fmt.Sprintf(`BEGIN DBMS_APPLICATION_INFO.SET_MODULE(%s, 'idle'); END;`, quote(options.Module)),
+			fmt.Sprintf(`BEGIN DBMS_APPLICATION_INFO.SET_CLIENT_INFO(%s); END;`, quote(options.ClientInfo)),
+		)
+	}
+
+	// Triggers are able to detect writes by SYS_CONTEXT('USERENV', 'CLIENT_IDENTIFIER')
+	if len(options.ClientIdentifier) > 0 {
+		statements = append(statements, fmt.Sprintf(`BEGIN DBMS_SESSION.SET_IDENTIFIER(%s); END;`, quote(options.ClientIdentifier)))
+	}
+
+	return statements
+}
+
+func getBatchAction(cmds []*DBCommand) string {
+
+	tables := make([]string, 0)
+	seen := make(map[string]bool)
+	for _, cmd := range cmds {
+		if seen[cmd.Table] {
+			continue
+		}
+
+		seen[cmd.Table] = true
+		tables = append(tables, cmd.Table)
+	}
+
+	if len(tables) == 1 {
+		return truncate(fmt.Sprintf("batch %d %s", len(cmds), tables[0]), maxActionLength)
+	}
+
+	return truncate(fmt.Sprintf("batch %d in %d tables", len(cmds), len(tables)), maxActionLength)
+}
+
+func getBulkAction(table string, count int) string {
+	return truncate(fmt.Sprintf("bulk %d %s", count, table), maxActionLength)
+}
+
+// setAction reports what the session is writing.
+func (writer *Writer) setAction(ctx context.Context, tx *sqlx.Tx, action string) {
+
+	if !writer.appInfo.Enabled {
+		return
+	}
+
+	ctx, cancel := writer.statementContext(ctx)
+	defer cancel()
+
+	// It is not worth failing the batch
+	_, err := tx.ExecContext(ctx, SetActionTemplate, action)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"action": action,
+		}).Warn(err)
+	}
+}
@@ -0,0 +1,488 @@
+package writer
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	gravity_sdk_types_record "github.com/BrobridgeOrg/gravity-sdk/types/record"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/oraerror"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/metrics"
+	buffered_input "github.com/cfsghost/buffered-input"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	BulkInsertTemplate      = `INSERT /*+ APPEND_VALUES */ INTO %s (%s) VALUES (%s)`
+	BulkInsertQueryTemplate = `INSERT /*+ APPEND */ INTO %s (%s) %s`
+	NoLoggingTemplate       = `ALTER TABLE %s NOLOGGING`
+	LoggingTemplate         = `ALTER TABLE %s LOGGING`
+	UnusableIndexTemplate   = `ALTER INDEX "%s" UNUSABLE`
+	RebuildIndexTemplate    = `ALTER INDEX "%s" REBUILD`
+)
+
+// Oracle allows 65535 bind variables at most in a statement
+const maxBindVariables = 65535
+
+type bulkBatch struct {
+	table    string
+	columns  []string
+	rows     [][]interface{}
+	commands []*DBCommand
+}
+
+// bulkTracker counts rows of tables which were pushed for bulk loading but not handled yet.
+type bulkTracker struct {
+	pending map[string]int
+	changed chan struct{}
+	mutex   sync.Mutex
+}
+
+func newBulkTracker() *bulkTracker {
+	return &bulkTracker{
+		pending: make(map[string]int),
+		changed: make(chan struct{}),
+	}
+}
+
+func (bt *bulkTracker) add(table string) {
+
+	bt.mutex.Lock()
+	defer bt.mutex.Unlock()
+
+	bt.pending[table]++
+}
+
+func (bt *bulkTracker) done(counts map[string]int) {
+
+	bt.mutex.Lock()
+	defer bt.mutex.Unlock()
+
+	for table, count := range counts {
+		bt.pending[table] -= count
+		if bt.pending[table] <= 0 {
+			delete(bt.pending, table)
+		}
+	}
+
+	// Wake up whoever is waiting
+	close(bt.changed)
+	bt.changed = make(chan struct{})
+}
+
+// check returns true if any of tables has pending rows, and channel which is closed once rows were handled.
+func (bt *bulkTracker) check(tables map[string]struct{}) (bool, <-chan struct{}) {
+
+	bt.mutex.Lock()
+	defer bt.mutex.Unlock()
+
+	for table := range tables {
+		if bt.pending[table] > 0 {
+			return true, bt.changed
+		}
+	}
+
+	return false, nil
+}
+
+func (writer *Writer) initBulkInput() {
+
+	viper.SetDefault("initialLoad.bulkChunkSize", 5000)
+	viper.SetDefault("initialLoad.bulkTimeout", 500)
+
+	opts := buffered_input.NewOptions()
+	opts.ChunkSize = viper.GetInt("initialLoad.bulkChunkSize")
+	opts.ChunkCount = 100
+	opts.Timeout = viper.GetDuration("initialLoad.bulkTimeout") * time.Millisecond
+	opts.Handler = writer.bulkChunkHandler
+	writer.bulkBuffer = buffered_input.NewBufferedInput(opts)
+}
+
+func (writer *Writer) BulkInsertRecord(reference interface{}, record *gravity_sdk_types_record.Record, tables []string) error {
+
+	recordDef, err := writer.GetDefinition(record)
+	if err != nil {
+		return err
+	}
+
+	dbCommand := dbCommandPool.Get().(*DBCommand)
+	dbCommand.Reference = reference
+	dbCommand.Record = record
+	dbCommand.Table = record.Table
+	dbCommand.QueryStr = ""
+	dbCommand.Args = recordDef.Values
+	dbCommand.RecordDef = recordDef
+	dbCommand.Tables = tables
+	dbCommand.bulk = true
+	dbCommand.PipelineID = 0
+	dbCommand.Sequence = 0
+	if ref, ok := reference.(database.EventReference); ok {
+		dbCommand.PipelineID = ref.GetPipelineID()
+		dbCommand.Sequence = ref.GetSequence()
+	}
+
+	writer.pushBulk(dbCommand)
+
+	return nil
+}
+
+func (writer *Writer) pushBulk(cmd *DBCommand) {
+	writer.bulkPending.add(cmd.Table)
+	writer.bulkBuffer.Push(cmd)
+}
+
+// flushBulk waits until pending bulk rows of tables of commands were written.
+func (writer *Writer) flushBulk(cmds []*DBCommand) {
+
+	tables := make(map[string]struct{})
+	for _, cmd := range cmds {
+		tables[cmd.Table] = struct{}{}
+	}
+
+	for {
+		pending, changed := writer.bulkPending.check(tables)
+		if !pending {
+			return
+		}
+
+		// Rows might be still in buffer
+		writer.bulkBuffer.Flush()
+
+		select {
+		case <-changed:
+		case <-writer.closing.Done():
+			return
+		}
+	}
+}
+
+func (writer *Writer) bulkChunkHandler(chunk []interface{}) {
+
+	writer.inflight.RLock()
+	defer writer.inflight.RUnlock()
+
+	if writer.isClosing() {
+		return
+	}
+
+	cmds := make([]*DBCommand, 0, len(chunk))
+	counts := make(map[string]int)
+	for _, request := range chunk {
+		cmd := request.(*DBCommand)
+		cmds = append(cmds, cmd)
+		counts[cmd.Table]++
+	}
+
+	// Rows are no longer pending once they were written, held or given up
+	defer writer.bulkPending.done(counts)
+
+	// Commands of paused tables are held until tables are resumed
+	cmds = writer.retrier.Hold(cmds)
+
+	// Records with the same columns can be written by one statement
+	batches := make([]*bulkBatch, 0)
+	batchMap := make(map[string]*bulkBatch)
+	for _, cmd := range cmds {
+		recordDef := cmd.RecordDef
+
+		columns := make([]string, 0, len(recordDef.ColumnDefs)+1)
+		row := make([]interface{}, 0, len(recordDef.ColumnDefs)+1)
+
+		if recordDef.HasPrimary {
+			columns = append(columns, `"`+recordDef.PrimaryColumn+`"`)
+			row = append(row, recordDef.Values["primary_val"])
+		}
+
+		for _, def := range recordDef.ColumnDefs {
+			columns = append(columns, `"`+def.ColumnName+`"`)
+			row = append(row, recordDef.Values[def.BindingName])
+		}
+
+		key := cmd.Table + ":" + strings.Join(columns, ",")
+		batch, ok := batchMap[key]
+		if !ok {
+			batch = &bulkBatch{
+				table:    cmd.Table,
+				columns:  columns,
+				rows:     make([][]interface{}, 0),
+				commands: make([]*DBCommand, 0),
+			}
+			batchMap[key] = batch
+			batches = append(batches, batch)
+		}
+
+		batch.rows = append(batch.rows, row)
+		batch.commands = append(batch.commands, cmd)
+	}
+
+	for _, batch := range batches {
+		writer.processBulk(batch)
+	}
+}
+
+func (writer *Writer) prepareBulkStatement(batch *bulkBatch, rows [][]interface{}) (string, []interface{}) {
+
+	colsStr := strings.Join(batch.columns, ",")
+
+	// Array binding which is the fastest way for direct-path insert, every argument is values of a column
+	if writer.arrayBinding {
+
+		placeholders := make([]string, 0, len(batch.columns))
+		args := make([]interface{}, 0, len(batch.columns))
+		for i := range batch.columns {
+			placeholders = append(placeholders, ":"+strconv.Itoa(i+1))
+
+			values := make([]driver.Value, 0, len(rows))
+			for _, row := range rows {
+				values = append(values, row[i])
+			}
+
+			args = append(args, values)
+		}
+
+		return fmt.Sprintf(BulkInsertTemplate, batch.table, colsStr, strings.Join(placeholders, ",")), args
+	}
+
+	// Multi-row query for driver which doesn't support array binding
+	selects := make([]string, 0, len(rows))
+	args := make([]interface{}, 0, len(rows)*len(batch.columns))
+	for _, row := range rows {
+		placeholders := make([]string, 0, len(row))
+		for _, value := range row {
+			args = append(args, value)
+			placeholders = append(placeholders, ":"+strconv.Itoa(len(args)))
+		}
+
+		selects = append(selects, "SELECT "+strings.Join(placeholders, ",")+" FROM dual")
+	}
+
+	return fmt.Sprintf(BulkInsertQueryTemplate, batch.table, colsStr, strings.Join(selects, " UNION ALL ")), args
+}
+
+func (writer *Writer) execBulk(table string, count int, sqlStr string, args []interface{}) (bool, error) {
+
+	ctx, cancel := writer.batchContext()
+	defer cancel()
+
+	conn, tx, release, err := writer.beginConn(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	defer release()
+
+	writer.setAction(ctx, tx, getBulkAction(table, count))
+
+	stmtCtx, stmtCancel := writer.statementContext(ctx)
+	defer stmtCancel()
+
+	if writer.arrayBinding {
+		columns := make([][]driver.Value, 0, len(args))
+		for _, arg := range args {
+			columns = append(columns, arg.([]driver.Value))
+		}
+
+		// Rows are bound on driver connection, it is in the same transaction
+		err = conn.Raw(func(driverConn interface{}) error {
+			return writer.driver.(ArrayBinder).BulkInsert(driverConn, sqlStr, count, columns)
+		})
+	} else {
+		_, err = tx.ExecContext(stmtCtx, sqlStr, args...)
+	}
+
+	if err != nil {
+		tx.Rollback()
+
+		// Retried as connection failure if the whole transaction was timed out
+		return ctx.Err() == nil, err
+	}
+
+	err = writer.checkFence(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return false, err
+	}
+
+	return false, nil
+}
+
+func (writer *Writer) processBulk(batch *bulkBatch) {
+
+	size := len(batch.rows)
+	if !writer.arrayBinding && len(batch.columns) > 0 {
+		size = maxBindVariables / len(batch.columns)
+	}
+
+	for start := 0; start < len(batch.rows); start += size {
+
+		end := start + size
+		if end > len(batch.rows) {
+			end = len(batch.rows)
+		}
+
+		sqlStr, args := writer.prepareBulkStatement(batch, batch.rows[start:end])
+		commands := batch.commands[start:end]
+
+		connBackoff := writer.retrier.connectionPolicy.NewBackoff()
+		stmtBackoff := writer.retrier.statementPolicy.NewBackoff()
+
+		// Direct-path insert requires commit before next insertion to the same table
+		for {
+
+			// Records will be written again after restart
+			if writer.isClosing() {
+				return
+			}
+
+			isStatement, err := writer.execBulk(batch.table, end-start, sqlStr, args)
+			if err == nil {
+				for _, cmd := range commands {
+					writer.complete(cmd)
+				}
+
+				break
+			}
+
+			// Another instance is writing
+			if err == ErrFenced {
+				log.Error(err)
+				writer.Abort()
+				return
+			}
+
+			category := oraerror.GetCategory(err)
+			metrics.DatabaseErrors.WithLabelValues(string(category)).Inc()
+
+			// Password might be changed
+			if isCredentialError(err) {
+				writer.reloadCredential()
+			}
+
+			log.WithFields(log.Fields{
+				"table":    batch.table,
+				"count":    end - start,
+				"category": category,
+			}).Error(err)
+
+			if !isStatement || category == oraerror.CategoryConnectivity {
+				if !connBackoff.WaitContext(writer.closing) {
+					if writer.isClosing() {
+						continue
+					}
+
+					writer.fail(fmt.Errorf("Exiting because retry attempts of connection were exhausted: %v", err))
+					return
+				}
+
+				log.Warn("Retry to write records to database by bulk ...")
+				continue
+			}
+
+			connBackoff.Reset()
+
+			// Retrying doesn't help if records are invalid
+			if !category.IsPermanent() && stmtBackoff.WaitContext(writer.closing) {
+				log.Warn("Retry to write records to database by bulk ...")
+				continue
+			}
+
+			if writer.isClosing() {
+				continue
+			}
+
+			// Records cannot be told apart in a statement, failed ones are found out by inserting one by one
+			log.WithFields(log.Fields{
+				"table": batch.table,
+				"count": end - start,
+			}).Warn("Falling back to row-by-row insertion")
+
+			for _, cmd := range commands {
+				cmd.QueryStr = getInsertSQL(cmd.Table, cmd.RecordDef)
+				cmd.bulk = false
+			}
+
+			writer.processData(commands)
+
+			break
+		}
+	}
+}
+
+func (writer *Writer) getIndexes(table string, status string) ([]string, error) {
+
+	indexes := make([]string, 0)
+	err := writer.db.Select(&indexes, `SELECT INDEX_NAME FROM USER_INDEXES WHERE TABLE_NAME = :1 AND UNIQUENESS = 'NONUNIQUE' AND PARTITIONED = 'NO' AND STATUS = :2`, strings.ToUpper(table), status)
+	if err != nil {
+		return nil, err
+	}
+
+	return indexes, nil
+}
+
+// PrepareBulkLoad is called before loading records to table by bulk.
+func (writer *Writer) PrepareBulkLoad(table string, options *database.BulkOptions) error {
+
+	if options.NoLogging {
+		err := writer.exec(fmt.Sprintf(NoLoggingTemplate, table))
+		if err != nil {
+			return err
+		}
+	}
+
+	if !options.DisableIndexes {
+		return nil
+	}
+
+	// Non-unique indexes will be skipped by DML if it is unusable
+	indexes, err := writer.getIndexes(table, "VALID")
+	if err != nil {
+		return err
+	}
+
+	for _, index := range indexes {
+		err := writer.exec(fmt.Sprintf(UnusableIndexTemplate, index))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// FinishBulkLoad is called after records were loaded to table.
+func (writer *Writer) FinishBulkLoad(table string, options *database.BulkOptions) error {
+
+	if options.DisableIndexes {
+		indexes, err := writer.getIndexes(table, "UNUSABLE")
+		if err != nil {
+			return err
+		}
+
+		for _, index := range indexes {
+			err := writer.exec(fmt.Sprintf(RebuildIndexTemplate, index))
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if options.NoLogging {
+		err := writer.exec(fmt.Sprintf(LoggingTemplate, table))
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
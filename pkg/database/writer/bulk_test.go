@@ -0,0 +1,133 @@
+package writer
+
+import (
+	"context"
+	"database/sql/driver"
+	"reflect"
+	"testing"
+	"time"
+
+	buffered_input "github.com/cfsghost/buffered-input"
+)
+
+func testBulkBatch() *bulkBatch {
+	return &bulkBatch{
+		table:   "USERS",
+		columns: []string{`"ID"`, `"NAME"`},
+		rows: [][]interface{}{
+			{int64(1), "a"},
+			{int64(2), "b"},
+		},
+	}
+}
+
+func TestPrepareBulkStatementArrayBinding(t *testing.T) {
+
+	writer := &Writer{
+		arrayBinding: true,
+	}
+
+	batch := testBulkBatch()
+	sqlStr, args := writer.prepareBulkStatement(batch, batch.rows)
+
+	expected := `INSERT /*+ APPEND_VALUES */ INTO USERS ("ID","NAME") VALUES (:1,:2)`
+	if sqlStr != expected {
+		t.Errorf("expected %s, got %s", expected, sqlStr)
+	}
+
+	// Every argument is values of a column
+	expectedArgs := []interface{}{
+		[]driver.Value{int64(1), int64(2)},
+		[]driver.Value{"a", "b"},
+	}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected %v, got %v", expectedArgs, args)
+	}
+}
+
+func TestPrepareBulkStatementMultiRow(t *testing.T) {
+
+	writer := &Writer{
+		arrayBinding: false,
+	}
+
+	batch := testBulkBatch()
+	sqlStr, args := writer.prepareBulkStatement(batch, batch.rows)
+
+	expected := `INSERT /*+ APPEND */ INTO USERS ("ID","NAME") SELECT :1,:2 FROM dual UNION ALL SELECT :3,:4 FROM dual`
+	if sqlStr != expected {
+		t.Errorf("expected %s, got %s", expected, sqlStr)
+	}
+
+	expectedArgs := []interface{}{int64(1), "a", int64(2), "b"}
+	if !reflect.DeepEqual(args, expectedArgs) {
+		t.Errorf("expected %v, got %v", expectedArgs, args)
+	}
+}
+
+func TestArrayBindingDriver(t *testing.T) {
+
+	d, err := GetDriver("ora")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := d.(ArrayBinder); !ok {
+		t.Errorf("expected ora driver to support array binding")
+	}
+}
+
+func TestFlushBulk(t *testing.T) {
+
+	writer := &Writer{
+		bulkPending: newBulkTracker(),
+	}
+
+	var cancel context.CancelFunc
+	writer.closing, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	// Rows are written only if they were flushed
+	written := make(chan string, 1)
+	opts := buffered_input.NewOptions()
+	opts.ChunkSize = 100
+	opts.Timeout = time.Hour
+	opts.Handler = func(chunk []interface{}) {
+		counts := make(map[string]int)
+		for _, request := range chunk {
+			counts[request.(*DBCommand).Table]++
+		}
+
+		writer.bulkPending.done(counts)
+		written <- "bulk"
+	}
+	writer.bulkBuffer = buffered_input.NewBufferedInput(opts)
+	defer writer.bulkBuffer.Close()
+
+	// Nothing to wait for other tables
+	writer.flushBulk([]*DBCommand{{Table: "ACCOUNTS"}})
+
+	writer.pushBulk(&DBCommand{Table: "USERS"})
+	writer.pushBulk(&DBCommand{Table: "USERS"})
+
+	done := make(chan struct{})
+	go func() {
+		writer.flushBulk([]*DBCommand{{Table: "ACCOUNTS"}, {Table: "USERS"}})
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second * 5):
+		t.Fatal("expected pending rows to be flushed")
+	}
+
+	if len(written) != 1 {
+		t.Errorf("expected rows to be written before commands")
+	}
+
+	// Closing writer stops waiting
+	writer.bulkPending.add("USERS")
+	cancel()
+	writer.flushBulk([]*DBCommand{{Table: "USERS"}})
+}
@@ -0,0 +1,125 @@
+package writer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/config"
+)
+
+var (
+	GetCurrentSchemaTemplate = `SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM dual`
+	GetColumnsTemplate       = `SELECT COLUMN_NAME FROM ALL_TAB_COLUMNS WHERE OWNER = :1 AND TABLE_NAME = :2`
+	GetSynonymTargetTemplate = `SELECT TABLE_OWNER, TABLE_NAME FROM ALL_SYNONYMS WHERE OWNER = :1 AND SYNONYM_NAME = :2`
+)
+
+// normalizeIdentifier returns name which is stored in dictionary, names are upper case unless they were quoted.
+func normalizeIdentifier(name string) string {
+
+	if len(name) >= 2 && strings.HasPrefix(name, `"`) && strings.HasSuffix(name, `"`) {
+		return name[1 : len(name)-1]
+	}
+
+	return strings.ToUpper(name)
+}
+
+// splitTableName returns owner and name of table, owner is empty if table is not qualified by schema.
+func splitTableName(table string) (string, string) {
+
+	parts := strings.SplitN(table, ".", 2)
+	if len(parts) == 1 {
+		return "", normalizeIdentifier(parts[0])
+	}
+
+	return normalizeIdentifier(parts[0]), normalizeIdentifier(parts[1])
+}
+
+func (writer *Writer) getColumns(owner string, table string) ([]string, error) {
+
+	columns := make([]string, 0)
+	err := writer.db.Select(&columns, GetColumnsTemplate, owner, table)
+	if err != nil {
+		return nil, err
+	}
+
+	return columns, nil
+}
+
+func (writer *Writer) getSynonymTarget(owner string, synonym string) (string, string, error) {
+
+	var target struct {
+		Owner string `db:"TABLE_OWNER"`
+		Table string `db:"TABLE_NAME"`
+	}
+
+	err := writer.db.Get(&target, GetSynonymTargetTemplate, owner, synonym)
+	if err == sql.ErrNoRows {
+		return "", "", nil
+	}
+
+	if err != nil {
+		return "", "", err
+	}
+
+	return target.Owner, target.Table, nil
+}
+
+// CheckTable verifies that table and specific columns exist.
+func (writer *Writer) CheckTable(table string, columns []string) error {
+
+	// Table belongs to current schema unless it was qualified by schema
+	owner, name := splitTableName(table)
+	if len(owner) == 0 {
+		err := writer.db.Get(&owner, GetCurrentSchemaTemplate)
+		if err != nil {
+			return err
+		}
+	}
+
+	found, err := writer.getColumns(owner, name)
+	if err != nil {
+		return err
+	}
+
+	// Table might be a synonym which is used by shadow table
+	if len(found) == 0 {
+		targetOwner, target, err := writer.getSynonymTarget(owner, name)
+		if err != nil {
+			return err
+		}
+
+		if len(target) > 0 {
+			found, err = writer.getColumns(targetOwner, target)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(found) == 0 {
+		return fmt.Errorf("table \"%s\": not found", table)
+	}
+
+	exists := make(map[string]bool, len(found))
+	for _, column := range found {
+		exists[column] = true
+	}
+
+	// Column names are quoted in statements, so it is case-sensitive
+	var problems config.Problems
+	for _, column := range columns {
+		if exists[column] {
+			continue
+		}
+
+		if exists[strings.ToUpper(column)] {
+			problems.Addf("table \"%s\": column \"%s\" not found, did you mean \"%s\"?", table, column, strings.ToUpper(column))
+			continue
+		}
+
+		problems.Addf("table \"%s\": column \"%s\" not found", table, column)
+	}
+
+	return problems.Err()
+}
@@ -0,0 +1,20 @@
+package writer
+
+import "testing"
+
+func TestSplitTableName(t *testing.T) {
+
+	cases := map[string][2]string{
+		"users":         {"", "USERS"},
+		"app.users":     {"APP", "USERS"},
+		`"App"."Users"`: {"App", "Users"},
+		`app."Users"`:   {"APP", "Users"},
+	}
+
+	for table, expected := range cases {
+		owner, name := splitTableName(table)
+		if owner != expected[0] || name != expected[1] {
+			t.Errorf("%s: expected %v, got [%s %s]", table, expected, owner, name)
+		}
+	}
+}
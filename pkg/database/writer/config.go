@@ -0,0 +1,193 @@
+package writer
+
+import (
+	"time"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/config"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/connstr"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/retry"
+	"github.com/spf13/viper"
+)
+
+type QueueOptions struct {
+	Enabled bool
+	Path    string
+
+	// Unit: MB
+	MaxSize int
+}
+
+type RetryOptions struct {
+	Connection     *retry.Policy
+	Statement      *retry.Policy
+	OnExhausted    retry.Action
+	PauseInterval  time.Duration
+	DeadLetterPath string
+}
+
+// Config is the configuration of writer.
+type Config struct {
+	Driver     string
+	Connection *connstr.Options
+	Pool       *PoolOptions
+	Timeout    *TimeoutOptions
+	Session    *SessionOptions
+	AppInfo    *AppInfoOptions
+	Retry      *RetryOptions
+	Queue      *QueueOptions
+	Heartbeat  *HeartbeatOptions
+}
+
+// LoadConfig loads and validates configuration of writer, all problems are returned at once.
+func LoadConfig() (*Config, error) {
+
+	var problems config.Problems
+
+	cfg := &Config{
+		Driver:     viper.GetString("database.driver"),
+		Connection: LoadConnectionOptions(),
+		Pool:       LoadPoolOptions(),
+		Timeout:    LoadTimeoutOptions(),
+		Session:    LoadSessionOptions(),
+		AppInfo:    LoadAppInfoOptions(),
+		Retry:      LoadRetryOptions(),
+		Queue:      LoadQueueOptions(),
+		Heartbeat:  LoadHeartbeatOptions(),
+	}
+
+	// Connection
+	driver, err := GetDriver(cfg.Driver)
+	if err != nil {
+		problems.Addf("database.driver: %v", err)
+	}
+
+	err = loadCredential(cfg.Connection)
+	if err != nil {
+		problems.Addf("database: %v", err)
+	}
+
+	_, err = cfg.Connection.GetConnectString()
+	if err != nil {
+		problems.Addf("database: %v", err)
+	} else if driver != nil {
+		_, err = driver.DSN(cfg.Connection)
+		if err != nil {
+			problems.Addf("database: %v", err)
+		}
+	}
+
+	if len(cfg.Connection.WalletDir) > 0 {
+		err = connstr.ValidateWallet(cfg.Connection.WalletDir)
+		if err != nil {
+			problems.Addf("database.wallet.dir: %v", err)
+		}
+	}
+
+	// Connection pool
+	if cfg.Pool.MaxOpenConns < 0 || cfg.Pool.MaxIdleConns < 0 {
+		problems.Addf("database.pool: maxOpenConns and maxIdleConns should not be less than 0")
+	}
+
+	if cfg.Pool.MaxLifetime < 0 || cfg.Pool.MaxIdleTime < 0 || cfg.Pool.KeepaliveInterval < 0 || cfg.Pool.ValidateTimeout < 0 {
+		problems.Addf("database.pool: maxLifetime, maxIdleTime, keepaliveInterval and validateTimeout should not be less than 0")
+	}
+
+	// Timeouts
+	if cfg.Timeout.Statement < 0 || cfg.Timeout.Batch < 0 {
+		problems.Addf("database.timeout: statement and batch should not be less than 0")
+	}
+
+	if cfg.Timeout.Shutdown < 0 {
+		problems.Addf("shutdown.timeout: should not be less than 0")
+	}
+
+	// Session
+	if cfg.Session.DDLLockTimeout < 0 {
+		problems.Addf("database.session.ddlLockTimeout: should not be less than 0")
+	}
+
+	// Retry policy
+	err = cfg.Retry.Connection.Validate()
+	if err != nil {
+		problems.Addf("retry.connection: %v", err)
+	}
+
+	err = cfg.Retry.Statement.Validate()
+	if err != nil {
+		problems.Addf("retry.statement: %v", err)
+	}
+
+	cfg.Retry.OnExhausted, err = retry.GetAction()
+	if err != nil {
+		problems.Addf("retry.onExhausted: %v", err)
+	}
+
+	if cfg.Retry.PauseInterval <= 0 {
+		problems.Addf("retry.pauseInterval: should be greater than 0")
+	}
+
+	// Queue
+	if cfg.Queue.Enabled {
+		if len(cfg.Queue.Path) == 0 {
+			problems.Addf("queue.path: path is required")
+		}
+
+		if cfg.Queue.MaxSize <= 0 {
+			problems.Addf("queue.maxSize: should be greater than 0")
+		}
+	}
+
+	problems.Add(cfg.Heartbeat.Validate())
+
+	return cfg, problems.Err()
+}
+
+func LoadConnectionOptions() *connstr.Options {
+
+	viper.SetDefault("database.tls.serverDNMatch", true)
+
+	return &connstr.Options{
+		ConnectString:  viper.GetString("database.connectString"),
+		Host:           viper.GetString("database.host"),
+		Port:           viper.GetInt("database.port"),
+		Hosts:          viper.GetStringSlice("database.hosts"),
+		LoadBalance:    viper.GetBool("database.loadBalance"),
+		Failover:       viper.GetBool("database.failover"),
+		ServiceName:    viper.GetString("database.serviceName"),
+		SID:            viper.GetString("database.sid"),
+		ConnectTimeout: viper.GetInt("database.connectTimeout"),
+		RetryCount:     viper.GetInt("database.retryCount"),
+		RetryDelay:     viper.GetInt("database.retryDelay"),
+		Param:          viper.GetString("database.param"),
+		TLS:            viper.GetBool("database.tls.enabled"),
+		ServerDNMatch:  viper.GetBool("database.tls.serverDNMatch"),
+		ServerCertDN:   viper.GetString("database.tls.serverCertDN"),
+		WalletDir:      viper.GetString("database.wallet.dir"),
+		ExternalAuth:   viper.GetBool("database.wallet.externalAuth"),
+	}
+}
+
+func LoadRetryOptions() *RetryOptions {
+
+	viper.SetDefault("retry.pauseInterval", 60)
+	viper.SetDefault("retry.deadLetterPath", "./deadletter.log")
+
+	return &RetryOptions{
+		Connection:     retry.LoadPolicy("retry.connection", retry.DefaultConnectionPolicy),
+		Statement:      retry.LoadPolicy("retry.statement", retry.DefaultStatementPolicy),
+		PauseInterval:  viper.GetDuration("retry.pauseInterval") * time.Second,
+		DeadLetterPath: viper.GetString("retry.deadLetterPath"),
+	}
+}
+
+func LoadQueueOptions() *QueueOptions {
+
+	viper.SetDefault("queue.path", "./queue")
+	viper.SetDefault("queue.maxSize", 1024)
+
+	return &QueueOptions{
+		Enabled: viper.GetBool("queue.enabled"),
+		Path:    viper.GetString("queue.path"),
+		MaxSize: viper.GetInt("queue.maxSize"),
+	}
+}
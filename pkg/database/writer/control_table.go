@@ -0,0 +1,60 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/oraerror"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+// createControlTable creates table which is used by transmitter itself (e.g. states, leases and heartbeats),
+// table which exists already is kept.
+func (writer *Writer) createControlTable(db *sqlx.DB, template string, table string, usage string) error {
+
+	ctx, cancel := writer.statementContext(context.Background())
+	defer cancel()
+
+	_, err := db.ExecContext(ctx, fmt.Sprintf(template, table))
+	if err == nil {
+		log.WithFields(log.Fields{
+			"table": table,
+		}).Infof("Created table for %s", usage)
+		return nil
+	}
+
+	// ORA-00955: name is already used by an existing object
+	if oraerror.Parse(err).Code == 955 {
+		return nil
+	}
+
+	return err
+}
+
+// upsertTemplate builds PL/SQL block which updates row, and inserts it if no row was updated. Row which was
+// inserted by others meanwhile is updated again. Binding variables are assigned to variables in order, so
+// values can be longer than 4000 bytes. Table is referred by %[1]s in statements.
+func upsertTemplate(variables []string, update string, insert string) string {
+
+	var b strings.Builder
+	b.WriteString("DECLARE\n")
+	for i, variable := range variables {
+		fmt.Fprintf(&b, "\t%s := :%d;\n", variable, i+1)
+	}
+
+	fmt.Fprintf(&b, `BEGIN
+	%[1]s;
+	IF SQL%%%%ROWCOUNT = 0 THEN
+		BEGIN
+			%[2]s;
+		EXCEPTION
+			WHEN DUP_VAL_ON_INDEX THEN
+				%[1]s;
+		END;
+	END IF;
+END;`, update, insert)
+
+	return b.String()
+}
@@ -0,0 +1,35 @@
+package writer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestUpsertTemplate(t *testing.T) {
+
+	template := upsertTemplate(
+		[]string{"v_key VARCHAR2(64)", "v_data CLOB"},
+		`UPDATE %[1]s SET DATA = v_data WHERE KEY = v_key`,
+		`INSERT INTO %[1]s (KEY, DATA) VALUES (v_key, v_data)`,
+	)
+
+	expected := `DECLARE
+	v_key VARCHAR2(64) := :1;
+	v_data CLOB := :2;
+BEGIN
+	UPDATE STATES SET DATA = v_data WHERE KEY = v_key;
+	IF SQL%ROWCOUNT = 0 THEN
+		BEGIN
+			INSERT INTO STATES (KEY, DATA) VALUES (v_key, v_data);
+		EXCEPTION
+			WHEN DUP_VAL_ON_INDEX THEN
+				UPDATE STATES SET DATA = v_data WHERE KEY = v_key;
+		END;
+	END IF;
+END;`
+
+	sqlStr := fmt.Sprintf(template, "STATES")
+	if sqlStr != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, sqlStr)
+	}
+}
@@ -0,0 +1,76 @@
+package writer
+
+import (
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/connstr"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/oraerror"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/secret"
+	log "github.com/sirupsen/logrus"
+)
+
+// Secrets which can be loaded from files (e.g. database.passwordFile)
+var credentialKeys = []string{
+	"database.username",
+	"database.password",
+}
+
+func isCredentialError(err error) bool {
+
+	e := oraerror.Parse(err)
+
+	// ORA-01017: invalid username/password, ORA-28001: the password has expired
+	return e.Code == 1017 || e.Code == 28001
+}
+
+func loadCredential(options *connstr.Options) error {
+
+	username, err := secret.Get("database.username")
+	if err != nil {
+		return err
+	}
+
+	password, err := secret.Get("database.password")
+	if err != nil {
+		return err
+	}
+
+	options.Username = username
+	options.Password = password
+
+	return nil
+}
+
+// reloadCredential reads credential again, and reconnects if it was changed.
+func (writer *Writer) reloadCredential() {
+
+	writer.credentialMutex.Lock()
+	defer writer.credentialMutex.Unlock()
+
+	username := writer.dbInfo.Username
+	password := writer.dbInfo.Password
+
+	err := loadCredential(writer.dbInfo)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if writer.dbInfo.Username == username && writer.dbInfo.Password == password {
+		return
+	}
+
+	dsn, err := writer.driver.DSN(writer.dbInfo)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"username": writer.dbInfo.Username,
+	}).Warn("Credential was changed, reconnecting to database")
+
+	writer.connector.SetDSN(dsn)
+
+	// Closing idle connections so that sessions are opened with new credential
+	writer.db.SetMaxIdleConns(0)
+	writer.db.SetMaxIdleConns(writer.pool.MaxIdleConns)
+}
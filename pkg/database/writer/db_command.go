@@ -1,8 +1,11 @@
 package writer
 
 import (
-	gravity_sdk_types_record "github.com/BrobridgeOrg/gravity-sdk/types/record"
 	"sync"
+	"time"
+
+	gravity_sdk_types_record "github.com/BrobridgeOrg/gravity-sdk/types/record"
+	"go.opentelemetry.io/otel/trace"
 )
 
 var dbCommandPool = sync.Pool{
@@ -14,12 +17,21 @@ var dbCommandPool = sync.Pool{
 type DBCommand struct {
 	PipelineID uint64
 	Sequence   uint64
+	EventTime  time.Time
+	ReceivedAt time.Time
 	Reference  interface{}
+	Table      string
 	Record     *gravity_sdk_types_record.Record
 	QueryStr   string
 	Args       map[string]interface{}
 	RecordDef  *gravity_sdk_types_record.RecordDef
 	Tables     []string
+
+	queueID uint64
+	bulk    bool
+
+	// Span of waiting for batch
+	queued trace.Span
 }
 
 func (cmd *DBCommand) GetReference() interface{} {
@@ -37,3 +49,27 @@ func (cmd *DBCommand) GetSequence() uint64 {
 func (cmd *DBCommand) GetTables() []string {
 	return cmd.Tables
 }
+
+// getBindColumns returns columns of bind variables, columns are unknown for commands which were replayed from queue.
+func (cmd *DBCommand) getBindColumns() map[string]string {
+
+	columns := make(map[string]string)
+
+	if cmd.Record != nil && len(cmd.Record.PrimaryKey) > 0 {
+		columns["primary_val"] = cmd.Record.PrimaryKey
+	}
+
+	if cmd.RecordDef == nil {
+		return columns
+	}
+
+	if cmd.RecordDef.HasPrimary {
+		columns["primary_val"] = cmd.RecordDef.PrimaryColumn
+	}
+
+	for _, def := range cmd.RecordDef.ColumnDefs {
+		columns[def.BindingName] = def.ColumnName
+	}
+
+	return columns
+}
@@ -0,0 +1,70 @@
+package writer
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/connstr"
+	"github.com/jmoiron/sqlx"
+)
+
+// Driver hides differences between database drivers, drivers are registered by build tags
+// so that driver requires cgo is not built with CGO_ENABLED=0. Statements are written with
+// Oracle bind variables (":name" and ":1") which are accepted by every driver.
+type Driver interface {
+
+	// Name which driver was registered to database/sql
+	Name() string
+
+	// DSN returns data source name which is built by connection options
+	DSN(options *connstr.Options) (string, error)
+}
+
+// ArrayBinder is implemented by driver which supports array binding for DML, rows are inserted
+// by one round trip. Connection is the raw connection of driver which has a transaction in progress.
+type ArrayBinder interface {
+	BulkInsert(conn interface{}, sqlStr string, rows int, columns [][]driver.Value) error
+}
+
+var drivers = make(map[string]Driver)
+
+// Drivers are preferred in order if no driver was specified
+var preferredDrivers = []string{
+	"oci8",
+	"ora",
+}
+
+func registerDriver(name string, d Driver) {
+	drivers[name] = d
+
+	// Named parameters of sqlx are passed to driver as they are
+	sqlx.BindDriver(d.Name(), sqlx.NAMED)
+}
+
+// GetDriver returns driver by name, driver which is available is returned if name is empty.
+func GetDriver(name string) (Driver, error) {
+
+	if len(name) == 0 {
+		for _, n := range preferredDrivers {
+			if d, ok := drivers[n]; ok {
+				return d, nil
+			}
+		}
+	}
+
+	d, ok := drivers[name]
+	if !ok {
+		names := make([]string, 0, len(drivers))
+		for n := range drivers {
+			names = append(names, n)
+		}
+
+		sort.Strings(names)
+
+		return nil, fmt.Errorf("Driver \"%s\" is not available, available drivers: %s", name, strings.Join(names, ", "))
+	}
+
+	return d, nil
+}
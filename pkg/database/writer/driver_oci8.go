@@ -0,0 +1,25 @@
+//go:build cgo
+// +build cgo
+
+package writer
+
+import (
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/connstr"
+	_ "github.com/mattn/go-oci8"
+)
+
+// oci8Driver is based on Oracle Instant Client, which requires cgo.
+type oci8Driver struct {
+}
+
+func init() {
+	registerDriver("oci8", &oci8Driver{})
+}
+
+func (d *oci8Driver) Name() string {
+	return "oci8"
+}
+
+func (d *oci8Driver) DSN(options *connstr.Options) (string, error) {
+	return options.Build()
+}
@@ -0,0 +1,105 @@
+package writer
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/connstr"
+	go_ora "github.com/sijms/go-ora/v2"
+)
+
+// oraDriver is written in pure Go, Oracle Instant Client is not required.
+type oraDriver struct {
+}
+
+func init() {
+	registerDriver("ora", &oraDriver{})
+}
+
+func (d *oraDriver) Name() string {
+	return "oracle"
+}
+
+func (d *oraDriver) BulkInsert(conn interface{}, sqlStr string, rows int, columns [][]driver.Value) error {
+
+	c, ok := conn.(*go_ora.Connection)
+	if !ok {
+		return fmt.Errorf("Unexpected connection %T for array binding", conn)
+	}
+
+	_, err := c.BulkInsert(sqlStr, rows, columns...)
+
+	return err
+}
+
+func (d *oraDriver) DSN(options *connstr.Options) (string, error) {
+
+	// No Oracle client to get credential from wallet
+	if options.ExternalAuth {
+		return "", errors.New("External authentication is not supported by ora driver")
+	}
+
+	connectString, err := options.GetConnectString()
+	if err != nil {
+		return "", err
+	}
+
+	urlOptions := make(map[string]string)
+
+	if options.TLS {
+		urlOptions["SSL"] = "true"
+		urlOptions["SSL VERIFY"] = strconv.FormatBool(options.ServerDNMatch)
+	}
+
+	if len(options.WalletDir) > 0 {
+		urlOptions["WALLET"] = options.WalletDir
+	}
+
+	if options.ConnectTimeout > 0 {
+		urlOptions["CONNECTION TIMEOUT"] = strconv.Itoa(options.ConnectTimeout)
+	}
+
+	var dsn string
+	if strings.HasPrefix(connectString, "(") {
+		dsn = go_ora.BuildJDBC(options.Username, options.Password, connectString, urlOptions)
+	} else {
+
+		// EZConnect in form of "host:port/service_name", TNS alias cannot be resolved without Oracle client
+		idx := strings.Index(connectString, "/")
+		if idx < 0 {
+			return "", fmt.Errorf("TNS alias \"%s\" is not supported by ora driver, connect descriptor is required", connectString)
+		}
+
+		addr, err := connstr.ParseAddress(connectString[:idx], connstr.DefaultPort)
+		if err != nil {
+			return "", err
+		}
+
+		service := connectString[idx+1:]
+		if len(options.ConnectString) == 0 && len(options.SID) > 0 {
+			urlOptions["SID"] = options.SID
+			service = ""
+		}
+
+		// Empty options makes URL end with "?"
+		if len(urlOptions) == 0 {
+			urlOptions = nil
+		}
+
+		dsn = go_ora.BuildUrl(addr.Host, addr.Port, service, options.Username, options.Password, urlOptions)
+	}
+
+	// Parameters are passed to driver as they are
+	if len(options.Param) > 0 {
+		if strings.Contains(dsn, "?") {
+			dsn += "&" + options.Param
+		} else {
+			dsn += "?" + options.Param
+		}
+	}
+
+	return dsn, nil
+}
@@ -0,0 +1,197 @@
+package writer
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+var (
+	CreateHeartbeatTableTemplate = `CREATE TABLE %s (
+	SUBSCRIBER_ID VARCHAR2(128) NOT NULL,
+	INSTANCE_ID VARCHAR2(128) NOT NULL,
+	LAST_EVENT_AT TIMESTAMP,
+	OLDEST_UNACKED_AT TIMESTAMP,
+	LAG_SECONDS NUMBER,
+	UPDATED_AT TIMESTAMP NOT NULL,
+	PRIMARY KEY (SUBSCRIBER_ID, INSTANCE_ID)
+)`
+
+	// Times are all in UTC, so they can be compared with SYS_EXTRACT_UTC(SYSTIMESTAMP)
+	PutHeartbeatTemplate = upsertTemplate(
+		[]string{
+			"v_subscriber VARCHAR2(128)",
+			"v_instance VARCHAR2(128)",
+			"v_event TIMESTAMP",
+			"v_oldest TIMESTAMP",
+			"v_lag NUMBER",
+		},
+		`UPDATE %[1]s SET LAST_EVENT_AT = v_event, OLDEST_UNACKED_AT = v_oldest, LAG_SECONDS = v_lag, UPDATED_AT = SYS_EXTRACT_UTC(SYSTIMESTAMP)
+		WHERE SUBSCRIBER_ID = v_subscriber AND INSTANCE_ID = v_instance`,
+		`INSERT INTO %[1]s (SUBSCRIBER_ID, INSTANCE_ID, LAST_EVENT_AT, OLDEST_UNACKED_AT, LAG_SECONDS, UPDATED_AT)
+			VALUES (v_subscriber, v_instance, v_event, v_oldest, v_lag, SYS_EXTRACT_UTC(SYSTIMESTAMP))`,
+	)
+)
+
+type HeartbeatOptions struct {
+	Enabled      bool
+	Table        string
+	Interval     time.Duration
+	SubscriberID string
+	InstanceID   string
+}
+
+func LoadHeartbeatOptions() *HeartbeatOptions {
+
+	hostname, _ := os.Hostname()
+
+	viper.SetDefault("database.heartbeat.table", "GRAVITY_HEARTBEAT")
+	viper.SetDefault("database.heartbeat.interval", 10)
+	viper.SetDefault("database.heartbeat.instanceID", hostname)
+
+	return &HeartbeatOptions{
+		Enabled:      viper.GetBool("database.heartbeat.enabled"),
+		Table:        viper.GetString("database.heartbeat.table"),
+		Interval:     viper.GetDuration("database.heartbeat.interval") * time.Second,
+		SubscriberID: viper.GetString("subscriber.subscriberID"),
+		InstanceID:   viper.GetString("database.heartbeat.instanceID"),
+	}
+}
+
+func (options *HeartbeatOptions) Validate() error {
+
+	if !options.Enabled {
+		return nil
+	}
+
+	if len(options.Table) == 0 {
+		return fmt.Errorf("database.heartbeat.table: table is required")
+	}
+
+	if options.Interval < time.Second {
+		return fmt.Errorf("database.heartbeat.interval: should not be less than 1")
+	}
+
+	if len(options.InstanceID) == 0 {
+		return fmt.Errorf("database.heartbeat.instanceID: instance ID is required")
+	}
+
+	return nil
+}
+
+// Heartbeat reports lag to a table of target database periodically, so that it can be
+// queried by monitoring jobs.
+type Heartbeat struct {
+	writer  *Writer
+	db      *sqlx.DB
+	options *HeartbeatOptions
+	closed  chan struct{}
+	done    chan struct{}
+}
+
+// startHeartbeat starts reporting lag if heartbeat was enabled.
+func (writer *Writer) startHeartbeat() error {
+
+	options := writer.config.Heartbeat
+	if !options.Enabled {
+		return nil
+	}
+
+	// Heartbeat is reported even if connections of writer were all busy
+	db := writer.openDedicatedDB()
+
+	heartbeat := &Heartbeat{
+		writer:  writer,
+		db:      db,
+		options: options,
+		closed:  make(chan struct{}),
+		done:    make(chan struct{}),
+	}
+
+	err := writer.createControlTable(db, CreateHeartbeatTableTemplate, options.Table, "heartbeat")
+	if err != nil {
+		db.Close()
+		return err
+	}
+
+	log.WithFields(log.Fields{
+		"table":    options.Table,
+		"interval": options.Interval,
+	}).Info("Reporting heartbeat to database")
+
+	writer.heartbeat = heartbeat
+
+	go heartbeat.run()
+
+	return nil
+}
+
+func (heartbeat *Heartbeat) run() {
+
+	defer close(heartbeat.done)
+
+	ticker := time.NewTicker(heartbeat.options.Interval)
+	defer ticker.Stop()
+
+	for {
+		err := heartbeat.beat()
+		if err != nil {
+			log.Warnf("Failed to report heartbeat: %v", err)
+		}
+
+		select {
+		case <-ticker.C:
+		case <-heartbeat.closed:
+			return
+		}
+	}
+}
+
+func getNullTime(t time.Time) interface{} {
+
+	if t.IsZero() {
+		return nil
+	}
+
+	return t.UTC()
+}
+
+func (heartbeat *Heartbeat) beat() error {
+
+	lastEventTime, oldest := heartbeat.writer.lag.status()
+
+	// Database has caught up if nothing is being written, lag is unknown if no event carries time
+	var lag interface{}
+	switch {
+	case !oldest.IsZero():
+		lag = time.Since(oldest).Seconds()
+	case !lastEventTime.IsZero():
+		lag = 0.0
+	}
+
+	ctx, cancel := heartbeat.writer.statementContext(context.Background())
+	defer cancel()
+
+	_, err := heartbeat.db.ExecContext(ctx, fmt.Sprintf(PutHeartbeatTemplate, heartbeat.options.Table),
+		heartbeat.options.SubscriberID,
+		heartbeat.options.InstanceID,
+		getNullTime(lastEventTime),
+		getNullTime(oldest),
+		lag,
+	)
+
+	return err
+}
+
+func (heartbeat *Heartbeat) Close() {
+
+	close(heartbeat.closed)
+	<-heartbeat.done
+
+	heartbeat.db.Close()
+}
@@ -0,0 +1,121 @@
+package writer
+
+import (
+	"sync"
+	"time"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/metrics"
+)
+
+// lagTracker measures how far database is behind events at source, commands without event time
+// (e.g. replayed from queue, or records without time) are not measured. Latency of transmitter is
+// measured from time of receiving instead.
+type lagTracker struct {
+	mutex    sync.Mutex
+	inflight map[interface{}]time.Time
+
+	// The newest event which was committed
+	lastEventTime time.Time
+}
+
+func newLagTracker() *lagTracker {
+	return &lagTracker{
+		inflight: make(map[interface{}]time.Time),
+	}
+}
+
+func (t *lagTracker) add(cmd *DBCommand) {
+
+	if cmd.EventTime.IsZero() {
+		return
+	}
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	t.inflight[cmd] = cmd.EventTime
+}
+
+func (t *lagTracker) remove(cmd *DBCommand) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	delete(t.inflight, cmd)
+}
+
+// move keeps tracking command by another key, e.g. by its handle while command was in queue.
+func (t *lagTracker) move(from interface{}, to interface{}) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	eventTime, ok := t.inflight[from]
+	if !ok {
+		return
+	}
+
+	delete(t.inflight, from)
+	t.inflight[to] = eventTime
+}
+
+func (t *lagTracker) committed(cmds []*DBCommand) {
+
+	now := time.Now()
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for _, cmd := range cmds {
+
+		if !cmd.ReceivedAt.IsZero() {
+			metrics.ReplicationLatencySeconds.WithLabelValues(cmd.Table).Observe(now.Sub(cmd.ReceivedAt).Seconds())
+		}
+
+		if cmd.EventTime.IsZero() {
+			continue
+		}
+
+		metrics.ReplicationLagSeconds.WithLabelValues(cmd.Table).Observe(now.Sub(cmd.EventTime).Seconds())
+
+		if cmd.EventTime.After(t.lastEventTime) {
+			t.lastEventTime = cmd.EventTime
+		}
+	}
+}
+
+// status returns the newest event which was committed and the oldest event which is not acknowledged yet.
+func (t *lagTracker) status() (time.Time, time.Time) {
+
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	var oldest time.Time
+	for _, eventTime := range t.inflight {
+		if oldest.IsZero() || eventTime.Before(oldest) {
+			oldest = eventTime
+		}
+	}
+
+	return t.lastEventTime, oldest
+}
+
+func (writer *Writer) trackLag() {
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-writer.closing.Done():
+			return
+		}
+
+		_, oldest := writer.lag.status()
+		if oldest.IsZero() {
+			metrics.OldestUnackedEventAgeSeconds.Set(0)
+			continue
+		}
+
+		metrics.OldestUnackedEventAgeSeconds.Set(time.Since(oldest).Seconds())
+	}
+}
@@ -0,0 +1,182 @@
+package writer
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/lease"
+	"github.com/jmoiron/sqlx"
+)
+
+var (
+	CreateLeaseTableTemplate = `CREATE TABLE %s (
+	GROUP_ID VARCHAR2(128) NOT NULL,
+	RESOURCE_NAME VARCHAR2(128) NOT NULL,
+	HOLDER VARCHAR2(128) NOT NULL,
+	EXPIRES_AT TIMESTAMP NOT NULL,
+	PRIMARY KEY (GROUP_ID, RESOURCE_NAME)
+)`
+
+	// Expiration is decided by clock of database, so clocks of transmitters don't matter
+	AcquireLeaseTemplate = upsertTemplate(
+		[]string{
+			"v_group VARCHAR2(128)",
+			"v_resource VARCHAR2(128)",
+			"v_holder VARCHAR2(128)",
+			"v_ttl NUMBER",
+		},
+		`UPDATE %[1]s SET HOLDER = v_holder, EXPIRES_AT = SYSTIMESTAMP + NUMTODSINTERVAL(v_ttl, 'SECOND')
+		WHERE GROUP_ID = v_group AND RESOURCE_NAME = v_resource AND (HOLDER = v_holder OR EXPIRES_AT < SYSTIMESTAMP)`,
+		`INSERT INTO %[1]s (GROUP_ID, RESOURCE_NAME, HOLDER, EXPIRES_AT)
+				VALUES (v_group, v_resource, v_holder, SYSTIMESTAMP + NUMTODSINTERVAL(v_ttl, 'SECOND'))`,
+	)
+
+	GetLeaseHolderTemplate = `SELECT HOLDER FROM %s WHERE GROUP_ID = :1 AND RESOURCE_NAME = :2 AND EXPIRES_AT >= SYSTIMESTAMP`
+	ListLeasesTemplate     = `SELECT RESOURCE_NAME, HOLDER FROM %s WHERE GROUP_ID = :1 AND RESOURCE_NAME LIKE :2 AND EXPIRES_AT >= SYSTIMESTAMP`
+	ReleaseLeaseTemplate   = `DELETE FROM %s WHERE GROUP_ID = :1 AND RESOURCE_NAME = :2 AND HOLDER = :3`
+
+	// Lease is locked until transaction was completed, so it cannot be taken by others before commit
+	FenceLeaseTemplate = `SELECT HOLDER FROM %s WHERE GROUP_ID = :1 AND RESOURCE_NAME = :2 AND EXPIRES_AT >= SYSTIMESTAMP FOR UPDATE`
+)
+
+// ErrFenced is returned by transactions which were not committed because lease was no longer held.
+var ErrFenced = errors.New("Lease was lost, writing was stopped")
+
+type leaseFence struct {
+	table    string
+	group    string
+	resource string
+	holder   string
+}
+
+type leaseRow struct {
+	Resource string `db:"RESOURCE_NAME"`
+	Holder   string `db:"HOLDER"`
+}
+
+// OracleLeaseStore keeps leases in a table of target database, leases are grouped so that
+// transmitters of different subscribers are able to share the same table.
+type OracleLeaseStore struct {
+	writer *Writer
+	db     *sqlx.DB
+	table  string
+	group  string
+}
+
+// NewLeaseStore creates lease store on specific table, table will be created if it doesn't exist.
+func (writer *Writer) NewLeaseStore(table string, group string) (lease.Store, error) {
+
+	// Leases should be renewed even if connections of writer were all busy
+	db := writer.openDedicatedDB()
+
+	store := &OracleLeaseStore{
+		writer: writer,
+		db:     db,
+		table:  table,
+		group:  group,
+	}
+
+	err := writer.createControlTable(db, CreateLeaseTableTemplate, table, "leases")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return store, nil
+}
+
+func (store *OracleLeaseStore) Acquire(resource string, holder string, ttl time.Duration) (bool, error) {
+
+	ctx, cancel := store.writer.statementContext(context.Background())
+	defer cancel()
+
+	_, err := store.db.ExecContext(ctx, fmt.Sprintf(AcquireLeaseTemplate, store.table), store.group, resource, holder, ttl.Seconds())
+	if err != nil {
+		return false, err
+	}
+
+	var current string
+	err = store.db.GetContext(ctx, &current, fmt.Sprintf(GetLeaseHolderTemplate, store.table), store.group, resource)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+
+	if err != nil {
+		return false, err
+	}
+
+	return current == holder, nil
+}
+
+func (store *OracleLeaseStore) Release(resource string, holder string) error {
+
+	ctx, cancel := store.writer.statementContext(context.Background())
+	defer cancel()
+
+	_, err := store.db.ExecContext(ctx, fmt.Sprintf(ReleaseLeaseTemplate, store.table), store.group, resource, holder)
+
+	return err
+}
+
+func (store *OracleLeaseStore) List(prefix string) (map[string]string, error) {
+
+	ctx, cancel := store.writer.statementContext(context.Background())
+	defer cancel()
+
+	rows := make([]leaseRow, 0)
+	err := store.db.SelectContext(ctx, &rows, fmt.Sprintf(ListLeasesTemplate, store.table), store.group, prefix+"%")
+	if err != nil {
+		return nil, err
+	}
+
+	leases := make(map[string]string, len(rows))
+	for _, row := range rows {
+		leases[row.Resource] = row.Holder
+	}
+
+	return leases, nil
+}
+
+func (store *OracleLeaseStore) Close() error {
+	return store.db.Close()
+}
+
+// SetFence makes every transaction check that lease is still held by holder before commit, so
+// that nothing is written once another instance took over.
+func (writer *Writer) SetFence(table string, group string, resource string, holder string) {
+	writer.fence = &leaseFence{
+		table:    table,
+		group:    group,
+		resource: resource,
+		holder:   holder,
+	}
+}
+
+func (writer *Writer) checkFence(ctx context.Context, tx *sqlx.Tx) error {
+
+	if writer.fence == nil {
+		return nil
+	}
+
+	ctx, cancel := writer.statementContext(ctx)
+	defer cancel()
+
+	var holder string
+	err := tx.GetContext(ctx, &holder, fmt.Sprintf(FenceLeaseTemplate, writer.fence.table), writer.fence.group, writer.fence.resource)
+	if err == sql.ErrNoRows {
+		return ErrFenced
+	}
+
+	if err != nil {
+		return err
+	}
+
+	if holder != writer.fence.holder {
+		return ErrFenced
+	}
+
+	return nil
+}
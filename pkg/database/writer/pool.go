@@ -0,0 +1,191 @@
+package writer
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+type PoolOptions struct {
+	MaxOpenConns      int
+	MaxIdleConns      int
+	MaxLifetime       time.Duration
+	MaxIdleTime       time.Duration
+	KeepaliveInterval time.Duration
+	Validate          bool
+	ValidateTimeout   time.Duration
+}
+
+func LoadPoolOptions() *PoolOptions {
+
+	viper.SetDefault("database.pool.maxOpenConns", 10)
+	viper.SetDefault("database.pool.maxIdleConns", 10)
+	viper.SetDefault("database.pool.maxLifetime", 0)
+	viper.SetDefault("database.pool.maxIdleTime", 0)
+	viper.SetDefault("database.pool.keepaliveInterval", 0)
+	viper.SetDefault("database.pool.validate", true)
+	viper.SetDefault("database.pool.validateTimeout", 5)
+
+	return &PoolOptions{
+		MaxOpenConns:      viper.GetInt("database.pool.maxOpenConns"),
+		MaxIdleConns:      viper.GetInt("database.pool.maxIdleConns"),
+		MaxLifetime:       viper.GetDuration("database.pool.maxLifetime") * time.Second,
+		MaxIdleTime:       viper.GetDuration("database.pool.maxIdleTime") * time.Second,
+		KeepaliveInterval: viper.GetDuration("database.pool.keepaliveInterval") * time.Second,
+		Validate:          viper.GetBool("database.pool.validate"),
+		ValidateTimeout:   viper.GetDuration("database.pool.validateTimeout") * time.Second,
+	}
+}
+
+func (writer *Writer) initPool(db *sqlx.DB) {
+
+	log.WithFields(log.Fields{
+		"maxOpenConns":      writer.pool.MaxOpenConns,
+		"maxIdleConns":      writer.pool.MaxIdleConns,
+		"maxLifetime":       writer.pool.MaxLifetime,
+		"maxIdleTime":       writer.pool.MaxIdleTime,
+		"keepaliveInterval": writer.pool.KeepaliveInterval,
+	}).Info("Initializing connection pool")
+
+	db.SetMaxOpenConns(writer.pool.MaxOpenConns)
+	db.SetMaxIdleConns(writer.pool.MaxIdleConns)
+	db.SetConnMaxLifetime(writer.pool.MaxLifetime)
+	db.SetConnMaxIdleTime(writer.pool.MaxIdleTime)
+}
+
+// openDedicatedDB opens a single connection which is not shared with writer, so its statements are not
+// blocked even if connections of writer were all busy. It is opened by the same connector, so new
+// credential is used as well.
+func (writer *Writer) openDedicatedDB() *sqlx.DB {
+
+	db := sqlx.NewDb(sql.OpenDB(writer.connector), writer.driver.Name())
+	db.SetMaxOpenConns(1)
+
+	return db
+}
+
+// keepalive pings idle connections periodically, so that idle sessions are not dropped by firewall.
+func (writer *Writer) keepalive() {
+
+	if writer.pool.KeepaliveInterval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(writer.pool.KeepaliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-writer.closing.Done():
+			return
+		}
+
+		writer.pingIdle()
+	}
+}
+
+// pingIdle pings every idle connection. Pool hands out the most recently used connection first, so idle
+// connections are all taken out before pinging, otherwise the same connection would be pinged again.
+func (writer *Writer) pingIdle() {
+
+	// Connections which are opened meanwhile are not idle ones
+	conns := make([]*sqlx.Conn, 0)
+	for writer.db.Stats().Idle > 0 && !writer.isClosing() {
+		conn, err := writer.db.Connx(writer.ctx)
+		if err != nil {
+			log.Warnf("Keepalive failed to get connection: %v", err)
+			break
+		}
+
+		conns = append(conns, conn)
+	}
+
+	// Dead connections are discarded, and the others are put back to pool
+	for _, conn := range conns {
+		err := writer.ping(writer.ctx, conn)
+		if err != nil {
+			log.Warnf("Keepalive ping failed: %v", err)
+			discard(conn)
+			continue
+		}
+
+		conn.Close()
+	}
+}
+
+type pinger interface {
+	PingContext(context.Context) error
+}
+
+func (writer *Writer) ping(ctx context.Context, p pinger) error {
+
+	ctx, cancel := withTimeout(ctx, writer.pool.ValidateTimeout)
+	defer cancel()
+
+	return p.PingContext(ctx)
+}
+
+// discard closes connection rather than putting it back to pool. Drivers don't always
+// report dead connection by driver.ErrBadConn, so it is reported on behalf of driver.
+func discard(conn *sqlx.Conn) {
+	conn.Raw(func(interface{}) error {
+		return driver.ErrBadConn
+	})
+	conn.Close()
+}
+
+// begin starts a transaction on connection which was validated, the release function
+// should be called once transaction was completed.
+func (writer *Writer) begin(ctx context.Context) (*sqlx.Tx, func(), error) {
+	_, tx, release, err := writer.beginConn(ctx)
+	return tx, release, err
+}
+
+// beginConn is the same as begin, and it returns the connection which transaction was started on.
+func (writer *Writer) beginConn(ctx context.Context) (*sqlx.Conn, *sqlx.Tx, func(), error) {
+
+	// Dead connections are evicted from pool by failed ping, trying until getting a good one
+	var err error
+	for i := 0; i <= writer.pool.MaxIdleConns; i++ {
+
+		var conn *sqlx.Conn
+		conn, err = writer.db.Connx(ctx)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+
+		if writer.pool.Validate {
+			err = writer.ping(ctx, conn)
+			if err != nil {
+				// Batch was timed out or canceled
+				if ctx.Err() != nil {
+					conn.Close()
+					return nil, nil, nil, err
+				}
+
+				discard(conn)
+
+				log.Warnf("Evicted dead connection: %v", err)
+				continue
+			}
+		}
+
+		tx, err := conn.BeginTxx(ctx, nil)
+		if err != nil {
+			conn.Close()
+			return nil, nil, nil, err
+		}
+
+		return conn, tx, func() {
+			conn.Close()
+		}, nil
+	}
+
+	return nil, nil, nil, err
+}
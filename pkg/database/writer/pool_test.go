@@ -0,0 +1,163 @@
+package writer
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// fakeConn never reports driver.ErrBadConn, like drivers which return errors of network as they are.
+type fakeConn struct {
+	dead   bool
+	closed bool
+	pings  int
+}
+
+func (c *fakeConn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("not supported")
+}
+
+func (c *fakeConn) Close() error {
+	c.closed = true
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c, nil
+}
+
+func (c *fakeConn) Commit() error {
+	return nil
+}
+
+func (c *fakeConn) Rollback() error {
+	return nil
+}
+
+func (c *fakeConn) Ping(context.Context) error {
+	c.pings++
+	if c.dead {
+		return errors.New("ORA-03113: end-of-file on communication channel")
+	}
+
+	return nil
+}
+
+type fakeConnector struct {
+	conns []*fakeConn
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) {
+	conn := &fakeConn{}
+	c.conns = append(c.conns, conn)
+	return conn, nil
+}
+
+func (c *fakeConnector) Driver() driver.Driver {
+	return nil
+}
+
+func TestBeginEvictsDeadConnection(t *testing.T) {
+
+	connector := &fakeConnector{}
+	db := sql.OpenDB(connector)
+	defer db.Close()
+
+	writer := &Writer{
+		db: sqlx.NewDb(db, "fake"),
+		pool: &PoolOptions{
+			MaxIdleConns:    2,
+			Validate:        true,
+			ValidateTimeout: time.Second,
+		},
+	}
+
+	// Connection was dropped while it was idle
+	err := db.Ping()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	connector.conns[0].dead = true
+
+	_, tx, release, err := writer.beginConn(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tx.Rollback()
+	release()
+
+	if len(connector.conns) != 2 {
+		t.Fatalf("expected a new connection, got %d connections", len(connector.conns))
+	}
+
+	if !connector.conns[0].closed {
+		t.Errorf("expected dead connection to be closed")
+	}
+
+	if db.Stats().Idle != 1 {
+		t.Errorf("expected only good connection in pool, got %d idle connections", db.Stats().Idle)
+	}
+}
+
+func TestPingIdlePingsEveryConnection(t *testing.T) {
+
+	connector := &fakeConnector{}
+	db := sql.OpenDB(connector)
+	db.SetMaxIdleConns(3)
+	defer db.Close()
+
+	writer := &Writer{
+		db:  sqlx.NewDb(db, "fake"),
+		ctx: context.Background(),
+		pool: &PoolOptions{
+			ValidateTimeout: time.Second,
+		},
+	}
+
+	var cancel context.CancelFunc
+	writer.closing, cancel = context.WithCancel(context.Background())
+	defer cancel()
+
+	// Three sessions are idle in pool
+	conns := make([]*sql.Conn, 0, 3)
+	for i := 0; i < 3; i++ {
+		conn, err := db.Conn(context.Background())
+		if err != nil {
+			t.Fatal(err)
+		}
+		conns = append(conns, conn)
+	}
+
+	for _, conn := range conns {
+		conn.Close()
+	}
+
+	connector.conns[1].dead = true
+
+	writer.pingIdle()
+
+	if len(connector.conns) != 3 {
+		t.Fatalf("expected no new connection, got %d connections", len(connector.conns))
+	}
+
+	for i, conn := range connector.conns {
+		if conn.pings != 1 {
+			t.Errorf("expected connection %d to be pinged once, got %d", i, conn.pings)
+		}
+	}
+
+	if !connector.conns[1].closed {
+		t.Errorf("expected dead connection to be closed")
+	}
+
+	if db.Stats().Idle != 2 {
+		t.Errorf("expected good connections back in pool, got %d idle connections", db.Stats().Idle)
+	}
+}
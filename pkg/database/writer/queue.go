@@ -0,0 +1,445 @@
+package writer
+
+import (
+	"bytes"
+	"encoding/gob"
+	"errors"
+	"sync"
+	"time"
+
+	broton "github.com/BrobridgeOrg/broton"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// QueuedCommand is what being persisted in queue for a command
+type QueuedCommand struct {
+	PipelineID uint64
+	Sequence   uint64
+	Table      string
+	QueryStr   string
+	Args       map[string]interface{}
+	Tables     []string
+}
+
+// queuedHandle is what being kept in memory for a command until it was acknowledged, command is
+// rebuilt from queue along with it.
+type queuedHandle struct {
+	reference  interface{}
+	eventTime  time.Time
+	receivedAt time.Time
+	queued     trace.Span
+}
+
+// replayKey identifies the event of command which was queued before restarting.
+type replayKey struct {
+	pipelineID uint64
+	sequence   uint64
+	table      string
+}
+
+// CommandQueue persists commands on disk until they were written to database.
+type CommandQueue struct {
+	writer    *Writer
+	store     *broton.Store
+	maxSize   int
+	size      int
+	lastID    uint64
+	readID    uint64
+	reading   int
+	limit     int
+	pending   map[uint64]*queuedHandle
+	sizes     map[uint64]int
+	replayed  map[uint64]uint64
+	replaying map[replayKey]uint64
+	waiting   map[uint64][]*DBCommand
+	closed    bool
+	mutex     sync.Mutex
+	cond      *sync.Cond
+}
+
+func init() {
+	gob.Register(time.Time{})
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+func NewCommandQueue(writer *Writer) *CommandQueue {
+
+	queue := &CommandQueue{
+		writer:    writer,
+		pending:   make(map[uint64]*queuedHandle),
+		sizes:     make(map[uint64]int),
+		replayed:  make(map[uint64]uint64),
+		replaying: make(map[replayKey]uint64),
+		waiting:   make(map[uint64][]*DBCommand),
+	}
+
+	queue.cond = sync.NewCond(&queue.mutex)
+
+	return queue
+}
+
+func (queue *CommandQueue) Init() error {
+
+	path := queue.writer.config.Queue.Path
+	queue.maxSize = queue.writer.config.Queue.MaxSize * 1024 * 1024
+
+	// Commands being read from disk are limited to save memory
+	queue.limit = viper.GetInt("bufferInput.chunkSize") * 4
+	if queue.limit <= 0 {
+		queue.limit = 4096
+	}
+
+	log.WithFields(log.Fields{
+		"path":    path,
+		"maxSize": queue.maxSize,
+	}).Info("Initializing command queue")
+
+	options := broton.NewOptions()
+	options.DatabasePath = path
+	bt, err := broton.NewBroton(options)
+	if err != nil {
+		return err
+	}
+
+	store, err := bt.GetStore("queue")
+	if err != nil {
+		return err
+	}
+
+	err = store.RegisterColumns([]string{"commands"})
+	if err != nil {
+		return err
+	}
+
+	queue.store = store
+
+	return queue.load()
+}
+
+func (queue *CommandQueue) load() error {
+
+	// Commands were left in queue since last time
+	obsoleted := make([][]byte, 0)
+	err := queue.store.List("commands", []byte(""), func(key []byte, value []byte) bool {
+
+		id := broton.BytesToUint64(key)
+		if queue.readID == 0 {
+			queue.readID = id
+		}
+
+		queue.lastID = id
+
+		var qcmd QueuedCommand
+		err := gob.NewDecoder(bytes.NewReader(value)).Decode(&qcmd)
+		if err != nil || qcmd.Sequence == 0 {
+			// Snapshot will be delivered again
+			obsoleted = append(obsoleted, append([]byte{}, key...))
+			return true
+		}
+
+		// Events will be delivered again as well, they should be ignored after replaying
+		if queue.replayed[qcmd.PipelineID] < qcmd.Sequence {
+			queue.replayed[qcmd.PipelineID] = qcmd.Sequence
+		}
+
+		queue.replaying[replayKey{qcmd.PipelineID, qcmd.Sequence, qcmd.Table}] = id
+
+		queue.sizes[id] = len(value)
+		queue.size += len(value)
+
+		return true
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, key := range obsoleted {
+		err := queue.store.Delete("commands", key)
+		if err != nil {
+			return err
+		}
+	}
+
+	if queue.readID == 0 {
+		queue.readID = 1
+	}
+
+	if len(queue.sizes) > 0 {
+		log.WithFields(log.Fields{
+			"count": len(queue.sizes),
+			"size":  queue.size,
+		}).Warn("Replaying commands which were queued before restarting")
+	}
+
+	return nil
+}
+
+// Replay returns true if the event was queued before restarting. If its command is still being replayed,
+// command of the event waits for it and is returned by Ack once it was committed, otherwise the event
+// was written already.
+func (queue *CommandQueue) Replay(cmd *DBCommand) (bool, bool) {
+
+	if cmd.Sequence == 0 {
+		return false, false
+	}
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	seq, ok := queue.replayed[cmd.PipelineID]
+	if !ok || cmd.Sequence > seq {
+		return false, false
+	}
+
+	id, ok := queue.replaying[replayKey{cmd.PipelineID, cmd.Sequence, cmd.Table}]
+	if !ok {
+		return true, false
+	}
+
+	queue.waiting[id] = append(queue.waiting[id], cmd)
+
+	return true, true
+}
+
+func (queue *CommandQueue) Push(cmd *DBCommand) error {
+
+	qcmd := QueuedCommand{
+		PipelineID: cmd.PipelineID,
+		Sequence:   cmd.Sequence,
+		Table:      cmd.Table,
+		QueryStr:   cmd.QueryStr,
+		Args:       cmd.Args,
+		Tables:     cmd.Tables,
+	}
+
+	var buf bytes.Buffer
+	err := gob.NewEncoder(&buf).Encode(&qcmd)
+	if err != nil {
+		return err
+	}
+
+	data := buf.Bytes()
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.closed {
+		return errors.New("Command queue was closed")
+	}
+
+	// Waiting for space
+	if queue.size > 0 && queue.size+len(data) > queue.maxSize {
+		log.WithFields(log.Fields{
+			"size":    queue.size,
+			"maxSize": queue.maxSize,
+		}).Warn("Command queue is full")
+
+		for !queue.closed && queue.size > 0 && queue.size+len(data) > queue.maxSize {
+			queue.cond.Wait()
+		}
+
+		if queue.closed {
+			return errors.New("Command queue was closed")
+		}
+	}
+
+	id := queue.lastID + 1
+	err = queue.store.Put("commands", broton.Uint64ToBytes(id), data)
+	if err != nil {
+		return err
+	}
+
+	queue.lastID = id
+	queue.size += len(data)
+	queue.sizes[id] = len(data)
+
+	// Only handle is kept for acknowledging, command will be rebuilt from queue
+	handle := &queuedHandle{
+		reference:  cmd.Reference,
+		eventTime:  cmd.EventTime,
+		receivedAt: cmd.ReceivedAt,
+		queued:     cmd.queued,
+	}
+
+	queue.pending[id] = handle
+	queue.writer.lag.move(cmd, handle)
+
+	if cmd.RecordDef != nil {
+		recordDefPool.Put(cmd.RecordDef)
+	}
+
+	*cmd = DBCommand{}
+	dbCommandPool.Put(cmd)
+
+	queue.cond.Broadcast()
+
+	return nil
+}
+
+func (queue *CommandQueue) next() (uint64, *queuedHandle, bool) {
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	for !queue.closed && (queue.readID > queue.lastID || queue.reading >= queue.limit) {
+		queue.cond.Wait()
+	}
+
+	if queue.closed {
+		return 0, nil, false
+	}
+
+	id := queue.readID
+	queue.readID++
+
+	// Command was dropped
+	if _, ok := queue.sizes[id]; !ok {
+		return id, nil, false
+	}
+
+	queue.reading++
+
+	return id, queue.pending[id], true
+}
+
+func (queue *CommandQueue) read(id uint64, handle *queuedHandle) (*DBCommand, error) {
+
+	queue.mutex.Lock()
+	if queue.closed {
+		queue.mutex.Unlock()
+		return nil, errors.New("Command queue was closed")
+	}
+
+	data, err := queue.store.GetBytes("commands", broton.Uint64ToBytes(id))
+	queue.mutex.Unlock()
+	if err != nil {
+		return nil, err
+	}
+
+	var qcmd QueuedCommand
+	err = gob.NewDecoder(bytes.NewReader(data)).Decode(&qcmd)
+	if err != nil {
+		return nil, err
+	}
+
+	cmd := dbCommandPool.Get().(*DBCommand)
+	*cmd = DBCommand{
+		PipelineID: qcmd.PipelineID,
+		Sequence:   qcmd.Sequence,
+		Table:      qcmd.Table,
+		QueryStr:   qcmd.QueryStr,
+		Args:       qcmd.Args,
+		Tables:     qcmd.Tables,
+		queueID:    id,
+	}
+
+	// Command which was queued before restarting has no handle
+	if handle != nil {
+		cmd.Reference = handle.reference
+		cmd.EventTime = handle.eventTime
+		cmd.ReceivedAt = handle.receivedAt
+		cmd.queued = handle.queued
+		queue.writer.lag.move(handle, cmd)
+	}
+
+	return cmd, nil
+}
+
+func (queue *CommandQueue) Run() {
+
+	for {
+		id, handle, ok := queue.next()
+		if !ok {
+			if queue.isClosed() {
+				return
+			}
+
+			continue
+		}
+
+		var cmd *DBCommand
+		for {
+			c, err := queue.read(id, handle)
+			if err == nil {
+				cmd = c
+				break
+			}
+
+			if queue.isClosed() {
+				return
+			}
+
+			log.Error(err)
+			<-time.After(time.Second * 5)
+		}
+
+		queue.writer.buffer.Push(cmd)
+	}
+}
+
+// Ack removes command from queue after it was written to database, commands of events which were waiting
+// for it are returned.
+func (queue *CommandQueue) Ack(cmd *DBCommand) []*DBCommand {
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	// Command will be replayed after restart
+	if queue.closed {
+		return nil
+	}
+
+	id := cmd.queueID
+	cmd.queueID = 0
+
+	err := queue.store.Delete("commands", broton.Uint64ToBytes(id))
+	if err != nil {
+		log.Error(err)
+	}
+
+	queue.size -= queue.sizes[id]
+	queue.reading--
+	delete(queue.sizes, id)
+	delete(queue.pending, id)
+
+	key := replayKey{cmd.PipelineID, cmd.Sequence, cmd.Table}
+	if queue.replaying[key] != id {
+		queue.cond.Broadcast()
+		return nil
+	}
+
+	// Events which are delivered later were written already
+	waiting := queue.waiting[id]
+	delete(queue.replaying, key)
+	delete(queue.waiting, id)
+
+	queue.cond.Broadcast()
+
+	return waiting
+}
+
+func (queue *CommandQueue) isClosed() bool {
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	return queue.closed
+}
+
+// Close flushes queue to disk, commands can no longer be pushed or read.
+func (queue *CommandQueue) Close() {
+
+	queue.mutex.Lock()
+	defer queue.mutex.Unlock()
+
+	if queue.closed {
+		return
+	}
+
+	queue.closed = true
+	queue.store.Close()
+	queue.cond.Broadcast()
+}
@@ -0,0 +1,175 @@
+package writer
+
+import (
+	"io/ioutil"
+	"os"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newTestQueue(t *testing.T, path string) *CommandQueue {
+
+	writer := &Writer{
+		lag: newLagTracker(),
+		config: &Config{
+			Queue: &QueueOptions{
+				Enabled: true,
+				Path:    path,
+				MaxSize: 1,
+			},
+		},
+	}
+
+	queue := NewCommandQueue(writer)
+	err := queue.Init()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return queue
+}
+
+func TestReplayQueue(t *testing.T) {
+
+	path, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	// Commands are left in queue by restarting
+	queue := newTestQueue(t, path)
+	for _, seq := range []uint64{2, 3} {
+		err := queue.Push(&DBCommand{
+			PipelineID: 1,
+			Sequence:   seq,
+			Table:      "USERS",
+			QueryStr:   "INSERT",
+			Args:       map[string]interface{}{"ID": int64(seq)},
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	queue.Close()
+
+	queue = newTestQueue(t, path)
+	defer queue.Close()
+
+	// Event was written before restarting
+	replayed, waiting := queue.Replay(&DBCommand{PipelineID: 1, Sequence: 1, Table: "USERS"})
+	if !replayed || waiting {
+		t.Errorf("expected event to be written already, got replayed=%v waiting=%v", replayed, waiting)
+	}
+
+	// Event was not queued
+	replayed, _ = queue.Replay(&DBCommand{PipelineID: 1, Sequence: 4, Table: "USERS"})
+	if replayed {
+		t.Errorf("expected new event not to be replayed")
+	}
+
+	replayed, _ = queue.Replay(&DBCommand{PipelineID: 2, Sequence: 2, Table: "USERS"})
+	if replayed {
+		t.Errorf("expected event of other pipeline not to be replayed")
+	}
+
+	// Event waits for command which is being replayed
+	live := &DBCommand{PipelineID: 1, Sequence: 2, Table: "USERS"}
+	replayed, waiting = queue.Replay(live)
+	if !replayed || !waiting {
+		t.Fatalf("expected event to wait for replaying, got replayed=%v waiting=%v", replayed, waiting)
+	}
+
+	id, handle, ok := queue.next()
+	if !ok {
+		t.Fatal("expected command to be read")
+	}
+
+	cmd, err := queue.read(id, handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd.Sequence != 2 || cmd.Args["ID"] != int64(2) {
+		t.Errorf("expected command of sequence 2, got %d %v", cmd.Sequence, cmd.Args)
+	}
+
+	completed := queue.Ack(cmd)
+	if len(completed) != 1 || completed[0] != live {
+		t.Errorf("expected waiting event to be completed, got %v", completed)
+	}
+
+	// Command was committed already
+	replayed, waiting = queue.Replay(&DBCommand{PipelineID: 1, Sequence: 2, Table: "USERS"})
+	if !replayed || waiting {
+		t.Errorf("expected event to be written already, got replayed=%v waiting=%v", replayed, waiting)
+	}
+}
+
+func TestQueueReleasesCommands(t *testing.T) {
+
+	path, err := ioutil.TempDir("", "queue")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(path)
+
+	queue := newTestQueue(t, path)
+	defer queue.Close()
+
+	// Database is down, so nothing is read from queue
+	var released int32
+	for i := 1; i <= 1000; i++ {
+		cmd := &DBCommand{
+			PipelineID: 1,
+			Sequence:   uint64(i),
+			EventTime:  time.Unix(int64(i), 0),
+			Reference:  i,
+			Table:      "USERS",
+			QueryStr:   strings.Repeat("INSERT", 100),
+			Args:       map[string]interface{}{"ID": int64(i)},
+		}
+		runtime.SetFinalizer(cmd, func(*DBCommand) {
+			atomic.AddInt32(&released, 1)
+		})
+
+		queue.writer.lag.add(cmd)
+		err := queue.Push(cmd)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Commands are no longer held once they were persisted
+	for i := 0; i < 50 && atomic.LoadInt32(&released) < 1000; i++ {
+		runtime.GC()
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if n := atomic.LoadInt32(&released); n < 1000 {
+		t.Errorf("expected queued commands to be released, %d of 1000 were released", n)
+	}
+
+	// Oldest event is still tracked while it is in queue
+	if _, oldest := queue.writer.lag.status(); !oldest.Equal(time.Unix(1, 0)) {
+		t.Errorf("expected oldest event to be tracked, got %v", oldest)
+	}
+
+	// Command is rebuilt from queue along with its handle
+	id, handle, ok := queue.next()
+	if !ok {
+		t.Fatal("expected command to be read")
+	}
+
+	cmd, err := queue.read(id, handle)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cmd.Reference != 1 || cmd.Sequence != 1 || cmd.Args["ID"] != int64(1) || !cmd.EventTime.Equal(time.Unix(1, 0)) {
+		t.Errorf("expected command of sequence 1, got %d %v %v", cmd.Sequence, cmd.Reference, cmd.Args)
+	}
+}
@@ -0,0 +1,229 @@
+package writer
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+type ReplayResult struct {
+	Replayed int
+	Failed   int
+
+	// Records which were rejected have no statement to be replayed
+	Skipped int
+}
+
+const (
+	argTypeTime   = "time"
+	argTypeBytes  = "bytes"
+	argTypeUint64 = "uint64"
+)
+
+// getArgTypes returns types of arguments which cannot be told from JSON.
+func getArgTypes(args map[string]interface{}) map[string]string {
+
+	types := make(map[string]string)
+	for name, value := range args {
+		switch value.(type) {
+		case time.Time:
+			types[name] = argTypeTime
+		case []byte:
+			types[name] = argTypeBytes
+		case uint64:
+			types[name] = argTypeUint64
+		}
+	}
+
+	if len(types) == 0 {
+		return nil
+	}
+
+	return types
+}
+
+// getArg restores argument by its type which was saved in dead letter.
+func getArg(value interface{}, argType string) (interface{}, error) {
+
+	var str string
+	switch v := value.(type) {
+	case string:
+		str = v
+	case json.Number:
+		str = v.String()
+	default:
+		return value, nil
+	}
+
+	switch argType {
+	case argTypeTime:
+		return time.Parse(time.RFC3339Nano, str)
+	case argTypeBytes:
+		return base64.StdEncoding.DecodeString(str)
+	case argTypeUint64:
+		return strconv.ParseUint(str, 10, 64)
+	}
+
+	return value, nil
+}
+
+// getArgs restores types of arguments which were lost in JSON.
+func getArgs(args map[string]interface{}, types map[string]string) (map[string]interface{}, error) {
+
+	for name, value := range args {
+
+		if argType, ok := types[name]; ok {
+			arg, err := getArg(value, argType)
+			if err != nil {
+				return nil, err
+			}
+
+			args[name] = arg
+			continue
+		}
+
+		num, ok := value.(json.Number)
+		if !ok {
+			continue
+		}
+
+		if i, err := num.Int64(); err == nil {
+			args[name] = i
+			continue
+		}
+
+		if f, err := num.Float64(); err == nil {
+			args[name] = f
+			continue
+		}
+
+		args[name] = num.String()
+	}
+
+	return args, nil
+}
+
+// parseDeadLetter decodes entry of dead letter file, numbers are kept as json.Number until types were restored.
+func parseDeadLetter(line []byte) (*DeadLetter, error) {
+
+	var entry DeadLetter
+	decoder := json.NewDecoder(bytes.NewReader(line))
+	decoder.UseNumber()
+	err := decoder.Decode(&entry)
+	if err != nil {
+		return nil, err
+	}
+
+	return &entry, nil
+}
+
+func (writer *Writer) replay(entry *DeadLetter) error {
+
+	args, err := getArgs(entry.Args, entry.Types)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := writer.batchContext()
+	defer cancel()
+
+	tx, release, err := writer.begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer release()
+
+	err = writer.namedExec(ctx, tx, entry.QueryStr, args)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Replay executes statements in dead letter file again, entries which cannot be replayed are
+// written to output file.
+func (writer *Writer) Replay(filename string, output string) (*ReplayResult, error) {
+
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	defer f.Close()
+
+	result := &ReplayResult{}
+	remaining := make([][]byte, 0)
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+
+		line := append([]byte{}, scanner.Bytes()...)
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
+
+		entry, err := parseDeadLetter(line)
+		if err != nil {
+			log.Error(err)
+			result.Failed++
+			remaining = append(remaining, line)
+			continue
+		}
+
+		if len(entry.QueryStr) == 0 {
+			result.Skipped++
+			remaining = append(remaining, line)
+			continue
+		}
+
+		err = writer.replay(entry)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"table":    entry.Table,
+				"pipeline": entry.PipelineID,
+				"sequence": entry.Sequence,
+			}).Error(err)
+
+			result.Failed++
+			remaining = append(remaining, line)
+			continue
+		}
+
+		result.Replayed++
+	}
+
+	err = scanner.Err()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(remaining) == 0 {
+		return result, nil
+	}
+
+	out, err := os.OpenFile(output, os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+
+	defer out.Close()
+
+	for _, line := range remaining {
+		_, err = out.Write(append(line, '\n'))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return result, nil
+}
@@ -0,0 +1,85 @@
+package writer
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDeadLetterArgs(t *testing.T) {
+
+	ts := time.Date(2021, 5, 4, 10, 20, 30, 123456789, time.FixedZone("", 8*3600))
+	args := map[string]interface{}{
+		"primary_val": int64(1),
+		"val_0":       ts,
+		"val_1":       []byte{0, 1, 2},
+		"val_2":       uint64(18446744073709551615),
+		"val_3":       1.5,
+		"val_4":       "2021-05-04T10:20:30Z",
+		"val_5":       nil,
+		"val_6":       int8(1),
+	}
+
+	data, err := json.Marshal(&DeadLetter{
+		QueryStr: "INSERT",
+		Args:     args,
+		Types:    getArgTypes(args),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entry, err := parseDeadLetter(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	restored, err := getArgs(entry.Args, entry.Types)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Strings which look like time are still strings
+	expected := map[string]interface{}{
+		"primary_val": int64(1),
+		"val_1":       []byte{0, 1, 2},
+		"val_2":       uint64(18446744073709551615),
+		"val_3":       1.5,
+		"val_4":       "2021-05-04T10:20:30Z",
+		"val_5":       nil,
+		"val_6":       int64(1),
+	}
+
+	restoredTime, ok := restored["val_0"].(time.Time)
+	if !ok || !restoredTime.Equal(ts) {
+		t.Errorf("expected time %v, got %#v", ts, restored["val_0"])
+	}
+	delete(restored, "val_0")
+
+	if !reflect.DeepEqual(restored, expected) {
+		t.Errorf("expected %#v, got %#v", expected, restored)
+	}
+}
+
+func TestDeadLetterArgsWithoutTypes(t *testing.T) {
+
+	// Entries which were written before types were saved
+	entry, err := parseDeadLetter([]byte(`{"query":"INSERT","args":{"val_0":1,"val_1":"a"}}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	args, err := getArgs(entry.Args, entry.Types)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := map[string]interface{}{
+		"val_0": int64(1),
+		"val_1": "a",
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %#v, got %#v", expected, args)
+	}
+}
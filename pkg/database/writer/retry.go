@@ -0,0 +1,281 @@
+package writer
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	gravity_sdk_types_record "github.com/BrobridgeOrg/gravity-sdk/types/record"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/oraerror"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/metrics"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/retry"
+	log "github.com/sirupsen/logrus"
+)
+
+type DeadLetter struct {
+	Time       time.Time              `json:"time"`
+	Table      string                 `json:"table"`
+	PipelineID uint64                 `json:"pipelineID"`
+	Sequence   uint64                 `json:"sequence"`
+	QueryStr   string                 `json:"query,omitempty"`
+	Args       map[string]interface{} `json:"args,omitempty"`
+	Types      map[string]string      `json:"types,omitempty"`
+	Error      string                 `json:"error"`
+	Code       int                    `json:"code,omitempty"`
+	Category   oraerror.Category      `json:"category"`
+}
+
+type pausedTable struct {
+	err      error
+	since    time.Time
+	commands []*DBCommand
+}
+
+type Retrier struct {
+	writer           *Writer
+	connectionPolicy *retry.Policy
+	statementPolicy  *retry.Policy
+	action           retry.Action
+	pauseInterval    time.Duration
+	deadLetterPath   string
+	deadLetterMutex  sync.Mutex
+	paused           map[string]*pausedTable
+	pauseMutex       sync.Mutex
+}
+
+func NewRetrier(writer *Writer) *Retrier {
+	return &Retrier{
+		writer: writer,
+		paused: make(map[string]*pausedTable),
+	}
+}
+
+func (r *Retrier) Init(options *RetryOptions) {
+
+	r.connectionPolicy = options.Connection
+	r.statementPolicy = options.Statement
+	r.action = options.OnExhausted
+	r.pauseInterval = options.PauseInterval
+	r.deadLetterPath = options.DeadLetterPath
+
+	log.WithFields(log.Fields{
+		"onExhausted": r.action,
+	}).Info("Initialized retry policy")
+}
+
+// Hold takes commands of paused tables away, and returns the rest.
+func (r *Retrier) Hold(cmds []*DBCommand) []*DBCommand {
+
+	r.pauseMutex.Lock()
+	defer r.pauseMutex.Unlock()
+
+	if len(r.paused) == 0 {
+		return cmds
+	}
+
+	rest := cmds[:0]
+	for _, cmd := range cmds {
+		pt, ok := r.paused[cmd.Table]
+		if !ok {
+			rest = append(rest, cmd)
+			continue
+		}
+
+		pt.commands = append(pt.commands, cmd)
+	}
+
+	return rest
+}
+
+func (r *Retrier) pause(table string, cmds []*DBCommand, err error) {
+
+	r.pauseMutex.Lock()
+	defer r.pauseMutex.Unlock()
+
+	pt, ok := r.paused[table]
+	if !ok {
+		log.WithFields(log.Fields{
+			"table": table,
+		}).Error("Pausing table because retry attempts were exhausted")
+
+		pt = &pausedTable{
+			since:    time.Now(),
+			commands: make([]*DBCommand, 0),
+		}
+		r.paused[table] = pt
+
+		metrics.PausedTables.WithLabelValues(table).Set(1)
+	}
+
+	pt.err = err
+
+	// Failed commands should be the first ones to retry
+	pt.commands = append(append([]*DBCommand{}, cmds...), pt.commands...)
+}
+
+// Run resumes paused tables periodically.
+func (r *Retrier) Run() {
+
+	ticker := time.NewTicker(r.pauseInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		r.resume()
+	}
+}
+
+func (r *Retrier) resume() {
+
+	r.pauseMutex.Lock()
+	paused := r.paused
+	r.paused = make(map[string]*pausedTable)
+	r.pauseMutex.Unlock()
+
+	for table, pt := range paused {
+
+		log.WithFields(log.Fields{
+			"table":    table,
+			"since":    pt.since,
+			"commands": len(pt.commands),
+			"error":    pt.err,
+		}).Warn("Resuming paused table")
+
+		metrics.PausedTables.WithLabelValues(table).Set(0)
+
+		// Table will be paused again if commands still failed
+		for _, cmd := range pt.commands {
+			if cmd.bulk {
+				r.writer.pushBulk(cmd)
+				continue
+			}
+
+			r.writer.buffer.Push(cmd)
+		}
+	}
+}
+
+func (r *Retrier) deadLetter(cmd *DBCommand, err error) error {
+
+	r.deadLetterMutex.Lock()
+	defer r.deadLetterMutex.Unlock()
+
+	e := oraerror.Parse(err)
+	entry := &DeadLetter{
+		Time:       time.Now(),
+		Table:      cmd.Table,
+		PipelineID: cmd.PipelineID,
+		Sequence:   cmd.Sequence,
+		QueryStr:   cmd.QueryStr,
+		Args:       cmd.Args,
+		Types:      getArgTypes(cmd.Args),
+		Error:      err.Error(),
+		Code:       e.Code,
+		Category:   e.Category,
+	}
+
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(r.deadLetterPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	if err != nil {
+		return err
+	}
+
+	metrics.DeadLetters.WithLabelValues(cmd.Table).Inc()
+
+	return nil
+}
+
+// Exhausted is called when retry attempts of commands of the same table were exhausted.
+func (r *Retrier) Exhausted(table string, cmds []*DBCommand, err error) {
+
+	log.WithFields(log.Fields{
+		"table":    table,
+		"commands": len(cmds),
+		"category": oraerror.GetCategory(err),
+		"action":   r.action,
+	}).Error(err)
+
+	switch r.action {
+	case retry.ActionExit:
+		// Commands are not acknowledged, and the rest are no longer written
+		r.writer.fail(fmt.Errorf("Exiting because retry attempts were exhausted: %v", err))
+	case retry.ActionPause:
+		r.pause(table, cmds, err)
+	case retry.ActionDeadLetter:
+		for _, cmd := range cmds {
+			for {
+				e := r.deadLetter(cmd, err)
+				if e == nil {
+					break
+				}
+
+				log.Error(e)
+				<-time.After(time.Second * 5)
+			}
+
+			r.writer.complete(cmd)
+		}
+	}
+}
+
+// Reject is called if record cannot be written by any way.
+func (writer *Writer) Reject(reference interface{}, record *gravity_sdk_types_record.Record, tables []string, err error) {
+
+	dbCommand := dbCommandPool.Get().(*DBCommand)
+	dbCommand.Reference = reference
+	dbCommand.Record = record
+	dbCommand.Table = record.Table
+	dbCommand.QueryStr = ""
+	dbCommand.Args = make(map[string]interface{}, len(record.Fields))
+	dbCommand.RecordDef = nil
+	dbCommand.Tables = tables
+	dbCommand.bulk = false
+	dbCommand.PipelineID = 0
+	dbCommand.Sequence = 0
+	if ref, ok := reference.(database.EventReference); ok {
+		dbCommand.PipelineID = ref.GetPipelineID()
+		dbCommand.Sequence = ref.GetSequence()
+	}
+
+	// Keep fields of record in dead letter
+	for _, field := range record.Fields {
+		dbCommand.Args[field.Name] = gravity_sdk_types_record.GetValue(field.Value)
+	}
+
+	log.WithFields(log.Fields{
+		"table":    dbCommand.Table,
+		"pipeline": dbCommand.PipelineID,
+		"sequence": dbCommand.Sequence,
+	}).Error(err)
+
+	if writer.retrier.action == retry.ActionExit {
+		writer.fail(fmt.Errorf("Exiting because record was rejected: %v", err))
+		return
+	}
+
+	// Nothing can be retried so record is always dead-lettered
+	for {
+		e := writer.retrier.deadLetter(dbCommand, err)
+		if e == nil {
+			break
+		}
+
+		log.Error(e)
+		<-time.After(time.Second * 5)
+	}
+
+	writer.complete(dbCommand)
+}
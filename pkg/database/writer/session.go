@@ -0,0 +1,265 @@
+package writer
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+type SessionOptions struct {
+	NLSDateFormat        string
+	NLSTimestampFormat   string
+	NLSTimestampTZFormat string
+	TimeZone             string
+	CurrentSchema        string
+	DDLLockTimeout       int
+	Statements           []string
+}
+
+// SessionConnector initializes every new session before it is used by connection pool.
+type SessionConnector struct {
+	driver     driver.Driver
+	dsn        string
+	statements []string
+	mutex      sync.RWMutex
+}
+
+func LoadSessionOptions() *SessionOptions {
+
+	viper.SetDefault("database.session.nlsDateFormat", "yyyy-mm-dd hh24:mi:ss")
+	viper.SetDefault("database.session.nlsTimestampFormat", "yyyy-mm-dd hh24:mi:ss.ff")
+
+	return &SessionOptions{
+		NLSDateFormat:        viper.GetString("database.session.nlsDateFormat"),
+		NLSTimestampFormat:   viper.GetString("database.session.nlsTimestampFormat"),
+		NLSTimestampTZFormat: viper.GetString("database.session.nlsTimestampTZFormat"),
+		TimeZone:             viper.GetString("database.session.timeZone"),
+		CurrentSchema:        viper.GetString("database.session.currentSchema"),
+		DDLLockTimeout:       viper.GetInt("database.session.ddlLockTimeout"),
+		Statements:           viper.GetStringSlice("database.session.statements"),
+	}
+}
+
+// GetStatements returns statements to initialize session.
+func (options *SessionOptions) GetStatements() []string {
+
+	statements := make([]string, 0)
+
+	if len(options.NLSDateFormat) > 0 {
+		statements = append(statements, fmt.Sprintf(`ALTER SESSION SET NLS_DATE_FORMAT='%s'`, options.NLSDateFormat))
+	}
+
+	if len(options.NLSTimestampFormat) > 0 {
+		statements = append(statements, fmt.Sprintf(`ALTER SESSION SET NLS_TIMESTAMP_FORMAT='%s'`, options.NLSTimestampFormat))
+	}
+
+	if len(options.NLSTimestampTZFormat) > 0 {
+		statements = append(statements, fmt.Sprintf(`ALTER SESSION SET NLS_TIMESTAMP_TZ_FORMAT='%s'`, options.NLSTimestampTZFormat))
+	}
+
+	if len(options.TimeZone) > 0 {
+		statements = append(statements, fmt.Sprintf(`ALTER SESSION SET TIME_ZONE='%s'`, options.TimeZone))
+	}
+
+	if len(options.CurrentSchema) > 0 {
+		statements = append(statements, fmt.Sprintf(`ALTER SESSION SET CURRENT_SCHEMA=%s`, options.CurrentSchema))
+	}
+
+	if options.DDLLockTimeout > 0 {
+		statements = append(statements, fmt.Sprintf(`ALTER SESSION SET DDL_LOCK_TIMEOUT=%d`, options.DDLLockTimeout))
+	}
+
+	return append(statements, options.Statements...)
+}
+
+func NewSessionConnector(driverName string, dsn string, statements []string) (*SessionConnector, error) {
+
+	// Getting driver which was registered
+	db, err := sql.Open(driverName, dsn)
+	if err != nil {
+		return nil, err
+	}
+
+	drv := db.Driver()
+	db.Close()
+
+	return &SessionConnector{
+		driver:     drv,
+		dsn:        dsn,
+		statements: statements,
+	}, nil
+}
+
+func (connector *SessionConnector) Connect(ctx context.Context) (driver.Conn, error) {
+
+	dsn := connector.GetDSN()
+
+	var conn driver.Conn
+	var err error
+	if dc, ok := connector.driver.(driver.DriverContext); ok {
+		c, e := dc.OpenConnector(dsn)
+		if e != nil {
+			return nil, e
+		}
+
+		conn, err = c.Connect(ctx)
+	} else {
+		conn, err = connector.driver.Open(dsn)
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	for _, sqlStr := range connector.statements {
+		err := execOnConn(ctx, conn, sqlStr)
+		if err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("Failed to initialize session by \"%s\": %v", sqlStr, err)
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"statements": len(connector.statements),
+	}).Debug("Initialized database session")
+
+	return conn, nil
+}
+
+func (connector *SessionConnector) GetDSN() string {
+
+	connector.mutex.RLock()
+	defer connector.mutex.RUnlock()
+
+	return connector.dsn
+}
+
+// SetDSN changes DSN for new sessions, sessions were opened already are not affected.
+func (connector *SessionConnector) SetDSN(dsn string) {
+
+	connector.mutex.Lock()
+	defer connector.mutex.Unlock()
+
+	connector.dsn = dsn
+}
+
+func (connector *SessionConnector) Driver() driver.Driver {
+	return connector.driver
+}
+
+func execOnConn(ctx context.Context, conn driver.Conn, sqlStr string) error {
+
+	if execer, ok := conn.(driver.ExecerContext); ok {
+		_, err := execer.ExecContext(ctx, sqlStr, nil)
+		if err != driver.ErrSkip {
+			return err
+		}
+	}
+
+	stmt, err := conn.Prepare(sqlStr)
+	if err != nil {
+		return err
+	}
+
+	defer stmt.Close()
+
+	if sc, ok := stmt.(driver.StmtExecContext); ok {
+		_, err = sc.ExecContext(ctx, nil)
+		return err
+	}
+
+	_, err = stmt.Exec(nil)
+
+	return err
+}
+
+// verifySession checks whether session was initialized as expected.
+func (writer *Writer) verifySession(options *SessionOptions, appInfo *AppInfoOptions) error {
+
+	conn, err := writer.db.Connx(context.Background())
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	expected := map[string]string{
+		"NLS_DATE_FORMAT":         options.NLSDateFormat,
+		"NLS_TIMESTAMP_FORMAT":    options.NLSTimestampFormat,
+		"NLS_TIMESTAMP_TZ_FORMAT": options.NLSTimestampTZFormat,
+	}
+
+	for parameter, value := range expected {
+
+		if len(value) == 0 {
+			continue
+		}
+
+		var current string
+		err := conn.GetContext(context.Background(), &current, `SELECT VALUE FROM NLS_SESSION_PARAMETERS WHERE PARAMETER = :1`, parameter)
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(current, value) {
+			return fmt.Errorf("%s of session is %s, expected %s", parameter, current, value)
+		}
+	}
+
+	if len(options.TimeZone) > 0 {
+		var current string
+		err := conn.GetContext(context.Background(), &current, `SELECT SESSIONTIMEZONE FROM dual`)
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(current, options.TimeZone) {
+			return fmt.Errorf("TIME_ZONE of session is %s, expected %s", current, options.TimeZone)
+		}
+	}
+
+	if len(options.CurrentSchema) > 0 {
+		var current string
+		err := conn.GetContext(context.Background(), &current, `SELECT SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA') FROM dual`)
+		if err != nil {
+			return err
+		}
+
+		if !strings.EqualFold(current, options.CurrentSchema) {
+			return fmt.Errorf("CURRENT_SCHEMA of session is %s, expected %s", current, options.CurrentSchema)
+		}
+	}
+
+	// Reading parameter requires privilege of V$PARAMETER
+	if options.DDLLockTimeout > 0 {
+		var current int
+		err := conn.GetContext(context.Background(), &current, `SELECT VALUE FROM V$PARAMETER WHERE NAME = 'ddl_lock_timeout'`)
+		if err != nil {
+			log.Warnf("Unable to verify DDL_LOCK_TIMEOUT of session: %v", err)
+		} else if current != options.DDLLockTimeout {
+			return fmt.Errorf("DDL_LOCK_TIMEOUT of session is %d, expected %d", current, options.DDLLockTimeout)
+		}
+	}
+
+	if appInfo.Enabled {
+		var module string
+		err := conn.GetContext(context.Background(), &module, `SELECT SYS_CONTEXT('USERENV', 'MODULE') FROM dual`)
+		if err != nil {
+			return err
+		}
+
+		if module != appInfo.Module {
+			return fmt.Errorf("MODULE of session is %s, expected %s", module, appInfo.Module)
+		}
+	}
+
+	log.Info("Verified database session")
+
+	return nil
+}
@@ -0,0 +1,247 @@
+package writer
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	DropTableTemplate      = `DROP TABLE %s PURGE`
+	ReplaceSynonymTemplate = `CREATE OR REPLACE SYNONYM %s FOR %s`
+)
+
+// Dependent objects of source table are renamed for shadow table, names without source table are generated.
+const cloneDeclarations = `
+	v_source VARCHAR2(128) := :1;
+	v_shadow VARCHAR2(128) := :2;
+	v_owner VARCHAR2(128) := SYS_CONTEXT('USERENV', 'CURRENT_SCHEMA');
+	v_ddl CLOB;
+	v_seq NUMBER := 0;
+
+	TYPE name_map IS TABLE OF VARCHAR2(128) INDEX BY VARCHAR2(128);
+	v_names name_map;
+
+	PROCEDURE add_name(p_name VARCHAR2) IS
+	BEGIN
+		IF INSTR(p_name, v_source) > 0 THEN
+			v_names(p_name) := REPLACE(p_name, v_source, v_shadow);
+		ELSE
+			v_seq := v_seq + 1;
+			v_names(p_name) := SUBSTR(v_shadow, 1, 120) || '_' || v_seq;
+		END IF;
+	END;
+
+	FUNCTION rewrite(p_ddl CLOB) RETURN CLOB IS
+		v_result CLOB := REPLACE(p_ddl, '"' || v_owner || '"."' || v_source || '"', '"' || v_owner || '"."' || v_shadow || '"');
+		v_name VARCHAR2(128) := v_names.FIRST;
+	BEGIN
+		WHILE v_name IS NOT NULL LOOP
+			v_result := REPLACE(v_result, '"' || v_name || '"', '"' || v_names(v_name) || '"');
+			v_name := v_names.NEXT(v_name);
+		END LOOP;
+		RETURN v_result;
+	END;
+`
+
+// CloneTableTemplate creates shadow table with indexes and constraints of source table.
+var CloneTableTemplate = `DECLARE` + cloneDeclarations + `
+BEGIN
+	DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'SQLTERMINATOR', FALSE);
+	DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'CONSTRAINTS', FALSE);
+	DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'REF_CONSTRAINTS', FALSE);
+
+	EXECUTE IMMEDIATE rewrite(DBMS_METADATA.GET_DDL('TABLE', v_source));
+
+	-- Indexes which were not created by constraints
+	FOR i IN (
+		SELECT INDEX_NAME FROM USER_INDEXES
+		WHERE TABLE_NAME = v_source AND INDEX_TYPE <> 'LOB' AND GENERATED = 'N'
+		AND INDEX_NAME NOT IN (SELECT CONSTRAINT_NAME FROM USER_CONSTRAINTS WHERE TABLE_NAME = v_source AND CONSTRAINT_TYPE IN ('P', 'U'))
+	) LOOP
+		v_ddl := DBMS_METADATA.GET_DDL('INDEX', i.INDEX_NAME);
+		add_name(i.INDEX_NAME);
+		EXECUTE IMMEDIATE rewrite(v_ddl);
+	END LOOP;
+
+	-- Constraints which are not NOT NULL constraints of columns
+	FOR c IN (
+		SELECT CONSTRAINT_NAME, CONSTRAINT_TYPE FROM USER_CONSTRAINTS
+		WHERE TABLE_NAME = v_source
+		AND (CONSTRAINT_TYPE IN ('P', 'U', 'R') OR (CONSTRAINT_TYPE = 'C' AND GENERATED = 'USER NAME'))
+		ORDER BY DECODE(CONSTRAINT_TYPE, 'P', 1, 'U', 2, 3)
+	) LOOP
+		IF c.CONSTRAINT_TYPE = 'R' THEN
+			v_ddl := DBMS_METADATA.GET_DDL('REF_CONSTRAINT', c.CONSTRAINT_NAME);
+		ELSE
+			v_ddl := DBMS_METADATA.GET_DDL('CONSTRAINT', c.CONSTRAINT_NAME);
+		END IF;
+		add_name(c.CONSTRAINT_NAME);
+		EXECUTE IMMEDIATE rewrite(v_ddl);
+	END LOOP;
+END;`
+
+// CloneGrantsTemplate copies grants and triggers of source table, triggers are created before swapping so they don't fire during initial load.
+var CloneGrantsTemplate = `DECLARE` + cloneDeclarations + `
+	v_pos NUMBER;
+BEGIN
+	DBMS_METADATA.SET_TRANSFORM_PARAM(DBMS_METADATA.SESSION_TRANSFORM, 'SQLTERMINATOR', FALSE);
+
+	FOR g IN (SELECT GRANTEE, PRIVILEGE, GRANTABLE FROM USER_TAB_PRIVS WHERE OWNER = v_owner AND TABLE_NAME = v_source) LOOP
+		EXECUTE IMMEDIATE 'GRANT ' || g.PRIVILEGE || ' ON "' || v_shadow || '" TO "' || g.GRANTEE || '"'
+			|| CASE WHEN g.GRANTABLE = 'YES' THEN ' WITH GRANT OPTION' END;
+	END LOOP;
+
+	FOR t IN (SELECT TRIGGER_NAME, STATUS FROM USER_TRIGGERS WHERE TABLE_NAME = v_source AND BASE_OBJECT_TYPE = 'TABLE') LOOP
+		v_ddl := DBMS_METADATA.GET_DDL('TRIGGER', t.TRIGGER_NAME);
+
+		-- Trigger is enabled by a separate statement at the end
+		v_pos := INSTR(v_ddl, 'ALTER TRIGGER', -1);
+		IF v_pos > 0 THEN
+			v_ddl := SUBSTR(v_ddl, 1, v_pos - 1);
+		END IF;
+
+		add_name(t.TRIGGER_NAME);
+		EXECUTE IMMEDIATE rewrite(v_ddl);
+
+		IF t.STATUS = 'DISABLED' THEN
+			EXECUTE IMMEDIATE 'ALTER TRIGGER "' || v_names(t.TRIGGER_NAME) || '" DISABLE';
+		END IF;
+	END LOOP;
+END;`
+
+func (writer *Writer) tableExists(table string) (bool, error) {
+
+	var count int
+	err := writer.db.Get(&count, `SELECT COUNT(*) FROM USER_TABLES WHERE TABLE_NAME = :1`, strings.ToUpper(table))
+	if err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+func (writer *Writer) getSynonymTable(synonym string) (string, error) {
+
+	var table string
+	err := writer.db.Get(&table, `SELECT TABLE_NAME FROM USER_SYNONYMS WHERE SYNONYM_NAME = :1`, strings.ToUpper(synonym))
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	if err != nil {
+		return "", err
+	}
+
+	return table, nil
+}
+
+func (writer *Writer) exec(sqlStr string) error {
+
+	log.WithFields(log.Fields{
+		"sql": sqlStr,
+	}).Info("Executing DDL")
+
+	_, err := writer.db.Exec(sqlStr)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// getShadowTables returns the table which is used by synonym and the other one.
+func (writer *Writer) getShadowTables(table string, options *database.ShadowOptions) (string, string, error) {
+
+	tables := options.Tables
+	if len(tables) == 0 {
+		tables = []string{table + "_A", table + "_B"}
+	}
+
+	current, err := writer.getSynonymTable(table)
+	if err != nil {
+		return "", "", err
+	}
+
+	if len(current) == 0 {
+		return "", "", fmt.Errorf("\"%s\" should be a synonym which points to \"%s\" or \"%s\", e.g. ALTER TABLE %s RENAME TO %s; CREATE SYNONYM %s FOR %s",
+			table, tables[0], tables[1], table, tables[0], table, tables[0])
+	}
+
+	switch current {
+	case strings.ToUpper(tables[0]):
+		return current, strings.ToUpper(tables[1]), nil
+	case strings.ToUpper(tables[1]):
+		return current, strings.ToUpper(tables[0]), nil
+	}
+
+	return "", "", fmt.Errorf("Synonym \"%s\" points to \"%s\" which is neither \"%s\" nor \"%s\"", table, current, tables[0], tables[1])
+}
+
+// PrepareShadowTable returns the table that snapshot records should be loaded into, records which were
+// loaded are kept only if keep is true.
+func (writer *Writer) PrepareShadowTable(table string, options *database.ShadowOptions, keep bool) (string, error) {
+
+	current, shadow, err := writer.getShadowTables(table, options)
+	if err != nil {
+		return "", err
+	}
+
+	exists, err := writer.tableExists(shadow)
+	if err != nil {
+		return "", err
+	}
+
+	// Records which were loaded will be merged by primary key
+	if keep && exists {
+		return shadow, nil
+	}
+
+	if exists {
+		err := writer.exec(fmt.Sprintf(DropTableTemplate, shadow))
+		if err != nil {
+			return "", err
+		}
+	}
+
+	log.WithFields(log.Fields{
+		"source": current,
+		"shadow": shadow,
+	}).Info("Cloning table with its indexes and constraints")
+
+	_, err = writer.db.Exec(CloneTableTemplate, current, shadow)
+	if err != nil {
+		return "", err
+	}
+
+	return shadow, nil
+}
+
+// SwapShadowTable makes the shadow table be the target table by replacing synonym.
+func (writer *Writer) SwapShadowTable(table string, shadow string, options *database.ShadowOptions) error {
+
+	current, err := writer.getSynonymTable(table)
+	if err != nil {
+		return err
+	}
+
+	// Synonym might be replaced already if swapping was interrupted
+	if current == shadow {
+		return nil
+	}
+
+	log.WithFields(log.Fields{
+		"source": current,
+		"shadow": shadow,
+	}).Info("Cloning grants and triggers")
+
+	_, err = writer.db.Exec(CloneGrantsTemplate, current, shadow)
+	if err != nil {
+		return err
+	}
+
+	return writer.exec(fmt.Sprintf(ReplaceSynonymTemplate, table, shadow))
+}
@@ -0,0 +1,376 @@
+package writer
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/state"
+	"github.com/jmoiron/sqlx"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	CreateStateTableTemplate = `CREATE TABLE %s (
+	SUBSCRIBER_ID VARCHAR2(128) NOT NULL,
+	STATE_COLUMN VARCHAR2(64) NOT NULL,
+	STATE_KEY VARCHAR2(512) NOT NULL,
+	SEQ NUMBER(20),
+	DATA CLOB,
+	UPDATED_AT TIMESTAMP DEFAULT SYSTIMESTAMP NOT NULL,
+	PRIMARY KEY (SUBSCRIBER_ID, STATE_COLUMN, STATE_KEY)
+)`
+
+	PutStateTemplate = upsertTemplate(
+		[]string{
+			"v_subscriber VARCHAR2(128)",
+			"v_column VARCHAR2(64)",
+			"v_key VARCHAR2(512)",
+			"v_seq NUMBER",
+			"v_data CLOB",
+		},
+		`UPDATE %[1]s SET SEQ = v_seq, DATA = v_data, UPDATED_AT = SYSTIMESTAMP
+		WHERE SUBSCRIBER_ID = v_subscriber AND STATE_COLUMN = v_column AND STATE_KEY = v_key`,
+		`INSERT INTO %[1]s (SUBSCRIBER_ID, STATE_COLUMN, STATE_KEY, SEQ, DATA)
+			VALUES (v_subscriber, v_column, v_key, v_seq, v_data)`,
+	)
+
+	GetStateTemplate    = `SELECT SEQ, DATA FROM %s WHERE SUBSCRIBER_ID = :1 AND STATE_COLUMN = :2 AND STATE_KEY = :3`
+	ListStateTemplate   = `SELECT STATE_KEY, SEQ, DATA FROM %s WHERE SUBSCRIBER_ID = :1 AND STATE_COLUMN = :2`
+	DeleteStateTemplate = `DELETE FROM %s WHERE SUBSCRIBER_ID = :1 AND STATE_COLUMN = :2 AND STATE_KEY = :3`
+)
+
+const pipelineStateColumn = "pipeline"
+
+type stateRow struct {
+	Key  string         `db:"STATE_KEY"`
+	Seq  sql.NullInt64  `db:"SEQ"`
+	Data sql.NullString `db:"DATA"`
+}
+
+// OracleStateBackend keeps states in a table of target database, so that transmitter has no local state.
+type OracleStateBackend struct {
+	writer       *Writer
+	db           *sqlx.DB
+	table        string
+	subscriberID string
+
+	// Sequences are updated by every event, so they are saved periodically, sequences which
+	// were saved along with records by batches are not saved again
+	pending    map[uint64]uint64
+	committed  map[uint64]uint64
+	mutex      sync.Mutex
+	flushMutex sync.Mutex
+	closed     chan struct{}
+	done       chan struct{}
+}
+
+// NewStateBackend creates backend which keeps states in specific table, table will be created if it doesn't exist.
+func (writer *Writer) NewStateBackend(table string, subscriberID string, flushInterval time.Duration) (state.Backend, error) {
+
+	// States are saved even if connections of writer were all busy
+	db := writer.openDedicatedDB()
+
+	backend := &OracleStateBackend{
+		writer:       writer,
+		db:           db,
+		table:        table,
+		subscriberID: subscriberID,
+		pending:      make(map[uint64]uint64),
+		committed:    make(map[uint64]uint64),
+		closed:       make(chan struct{}),
+		done:         make(chan struct{}),
+	}
+
+	err := writer.createControlTable(db, CreateStateTableTemplate, table, "states")
+	if err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"table":        table,
+		"subscriberID": subscriberID,
+	}).Info("Initialized state backend on database")
+
+	go backend.run(flushInterval)
+
+	writer.states = backend
+
+	return backend, nil
+}
+
+func (backend *OracleStateBackend) run(interval time.Duration) {
+
+	defer close(backend.done)
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := backend.Flush()
+			if err != nil {
+				log.Errorf("Failed to save states of pipelines: %v", err)
+			}
+		case <-backend.closed:
+			return
+		}
+	}
+}
+
+func (backend *OracleStateBackend) exec(query string, args ...interface{}) error {
+
+	ctx, cancel := backend.writer.statementContext(context.Background())
+	defer cancel()
+
+	_, err := backend.db.ExecContext(ctx, fmt.Sprintf(query, backend.table), args...)
+
+	return err
+}
+
+func (backend *OracleStateBackend) put(column string, key string, seq interface{}, data interface{}) error {
+	return backend.exec(PutStateTemplate, backend.subscriberID, column, key, seq, data)
+}
+
+func (backend *OracleStateBackend) get(column string, key string) (*stateRow, error) {
+
+	ctx, cancel := backend.writer.statementContext(context.Background())
+	defer cancel()
+
+	var row stateRow
+	err := backend.db.QueryRowxContext(ctx, fmt.Sprintf(GetStateTemplate, backend.table), backend.subscriberID, column, key).Scan(&row.Seq, &row.Data)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &row, nil
+}
+
+func (backend *OracleStateBackend) list(column string) ([]stateRow, error) {
+
+	ctx, cancel := backend.writer.statementContext(context.Background())
+	defer cancel()
+
+	rows := make([]stateRow, 0)
+	err := backend.db.SelectContext(ctx, &rows, fmt.Sprintf(ListStateTemplate, backend.table), backend.subscriberID, column)
+	if err != nil {
+		return nil, err
+	}
+
+	return rows, nil
+}
+
+func (backend *OracleStateBackend) GetSequence(pipelineID uint64) (uint64, error) {
+
+	backend.mutex.Lock()
+	sequence, ok := backend.pending[pipelineID]
+	backend.mutex.Unlock()
+
+	if ok {
+		return sequence, nil
+	}
+
+	row, err := backend.get(pipelineStateColumn, strconv.FormatUint(pipelineID, 10))
+	if err != nil {
+		return 0, err
+	}
+
+	if row == nil {
+		return 0, nil
+	}
+
+	return uint64(row.Seq.Int64), nil
+}
+
+func (backend *OracleStateBackend) PutSequence(pipelineID uint64, sequence uint64) error {
+
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	backend.pending[pipelineID] = sequence
+
+	return nil
+}
+
+func (backend *OracleStateBackend) DeleteSequence(pipelineID uint64) error {
+
+	backend.mutex.Lock()
+	delete(backend.pending, pipelineID)
+	backend.mutex.Unlock()
+
+	return backend.exec(DeleteStateTemplate, backend.subscriberID, pipelineStateColumn, strconv.FormatUint(pipelineID, 10))
+}
+
+func (backend *OracleStateBackend) ListSequences() (map[uint64]uint64, error) {
+
+	rows, err := backend.list(pipelineStateColumn)
+	if err != nil {
+		return nil, err
+	}
+
+	sequences := make(map[uint64]uint64, len(rows))
+	for _, row := range rows {
+		pipelineID, err := strconv.ParseUint(row.Key, 10, 64)
+		if err != nil {
+			continue
+		}
+
+		sequences[pipelineID] = uint64(row.Seq.Int64)
+	}
+
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	for pipelineID, sequence := range backend.pending {
+		sequences[pipelineID] = sequence
+	}
+
+	return sequences, nil
+}
+
+func (backend *OracleStateBackend) Get(column string, key string) ([]byte, error) {
+
+	row, err := backend.get(column, key)
+	if err != nil {
+		return nil, err
+	}
+
+	if row == nil || !row.Data.Valid {
+		return nil, nil
+	}
+
+	return []byte(row.Data.String), nil
+}
+
+func (backend *OracleStateBackend) Put(column string, key string, data []byte) error {
+	return backend.put(column, key, nil, string(data))
+}
+
+func (backend *OracleStateBackend) Delete(column string, key string) error {
+	return backend.exec(DeleteStateTemplate, backend.subscriberID, column, key)
+}
+
+func (backend *OracleStateBackend) List(column string) (map[string][]byte, error) {
+
+	rows, err := backend.list(column)
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string][]byte, len(rows))
+	for _, row := range rows {
+		states[row.Key] = []byte(row.Data.String)
+	}
+
+	return states, nil
+}
+
+// saveSequences saves the last sequence of every pipeline of commands in transaction of batch,
+// so that sequences never fall behind or go beyond records which were written.
+func (writer *Writer) saveSequences(ctx context.Context, tx *sqlx.Tx, cmds []*DBCommand) (map[uint64]uint64, error) {
+
+	if writer.states == nil {
+		return nil, nil
+	}
+
+	// Snapshot records have no sequence
+	sequences := make(map[uint64]uint64)
+	for _, cmd := range cmds {
+		if cmd.Sequence > sequences[cmd.PipelineID] {
+			sequences[cmd.PipelineID] = cmd.Sequence
+		}
+	}
+
+	backend := writer.states
+	sqlStr := fmt.Sprintf(PutStateTemplate, backend.table)
+	for pipelineID, sequence := range sequences {
+
+		stmtCtx, cancel := writer.statementContext(ctx)
+		_, err := tx.ExecContext(stmtCtx, sqlStr, backend.subscriberID, pipelineStateColumn, strconv.FormatUint(pipelineID, 10), int64(sequence), nil)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return sequences, nil
+}
+
+// commitSequences is called once sequences were saved by batch.
+func (backend *OracleStateBackend) commitSequences(sequences map[uint64]uint64) {
+
+	backend.mutex.Lock()
+	defer backend.mutex.Unlock()
+
+	for pipelineID, sequence := range sequences {
+		if sequence > backend.committed[pipelineID] {
+			backend.committed[pipelineID] = sequence
+		}
+	}
+}
+
+// Flush saves sequences which were updated since last flush.
+func (backend *OracleStateBackend) Flush() error {
+
+	backend.flushMutex.Lock()
+	defer backend.flushMutex.Unlock()
+
+	backend.mutex.Lock()
+	pending := backend.pending
+	backend.pending = make(map[uint64]uint64)
+	backend.mutex.Unlock()
+
+	// Batch has saved the same or later sequence already
+	backend.mutex.Lock()
+	for pipelineID, sequence := range pending {
+		if sequence <= backend.committed[pipelineID] {
+			delete(pending, pipelineID)
+		}
+	}
+	backend.mutex.Unlock()
+
+	for pipelineID, sequence := range pending {
+
+		err := backend.put(pipelineStateColumn, strconv.FormatUint(pipelineID, 10), int64(sequence), nil)
+		if err == nil {
+			delete(pending, pipelineID)
+			continue
+		}
+
+		// Keeping sequences which were not saved unless they were updated again
+		backend.mutex.Lock()
+		for id, seq := range pending {
+			if _, ok := backend.pending[id]; !ok {
+				backend.pending[id] = seq
+			}
+		}
+		backend.mutex.Unlock()
+
+		return err
+	}
+
+	return nil
+}
+
+// Close saves sequences and closes connection.
+func (backend *OracleStateBackend) Close() error {
+
+	close(backend.closed)
+	<-backend.done
+
+	err := backend.Flush()
+	if err != nil {
+		log.Errorf("Failed to save states of pipelines: %v", err)
+	}
+
+	return backend.db.Close()
+}
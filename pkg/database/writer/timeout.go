@@ -0,0 +1,50 @@
+package writer
+
+import (
+	"context"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type TimeoutOptions struct {
+	Statement time.Duration
+	Batch     time.Duration
+	Shutdown  time.Duration
+}
+
+func LoadTimeoutOptions() *TimeoutOptions {
+
+	viper.SetDefault("database.timeout.statement", 60)
+	viper.SetDefault("database.timeout.batch", 300)
+	viper.SetDefault("shutdown.timeout", 30)
+
+	return &TimeoutOptions{
+		Statement: viper.GetDuration("database.timeout.statement") * time.Second,
+		Batch:     viper.GetDuration("database.timeout.batch") * time.Second,
+		Shutdown:  viper.GetDuration("shutdown.timeout") * time.Second,
+	}
+}
+
+func withTimeout(parent context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+
+	// No timeout
+	if timeout <= 0 {
+		return context.WithCancel(parent)
+	}
+
+	return context.WithTimeout(parent, timeout)
+}
+
+// batchContext returns context for a transaction, which is canceled once writer was closed.
+func (writer *Writer) batchContext() (context.Context, context.CancelFunc) {
+	return withTimeout(writer.ctx, writer.timeout.Batch)
+}
+
+func (writer *Writer) statementContext(ctx context.Context) (context.Context, context.CancelFunc) {
+	return withTimeout(ctx, writer.timeout.Statement)
+}
+
+func (writer *Writer) isClosing() bool {
+	return writer.closing.Err() != nil
+}
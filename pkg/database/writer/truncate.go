@@ -1,6 +1,10 @@
 package writer
 
-import "fmt"
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+)
 
 func (writer *Writer) Truncate(table string) error {
 
@@ -12,3 +16,22 @@ func (writer *Writer) Truncate(table string) error {
 
 	return nil
 }
+
+var GetEstimatedRowCountTemplate = `SELECT NVL(NUM_ROWS, 0) FROM USER_TABLES
+WHERE TABLE_NAME = NVL((SELECT TABLE_NAME FROM USER_SYNONYMS WHERE SYNONYM_NAME = :1), :2)`
+
+func (writer *Writer) GetEstimatedRowCount(table string) (uint64, error) {
+
+	// Using statistics of table, or the table which is used by synonym
+	var count uint64
+	err := writer.db.Get(&count, GetEstimatedRowCountTemplate, strings.ToUpper(table), strings.ToUpper(table))
+	if err == sql.ErrNoRows {
+		return 0, nil
+	}
+
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
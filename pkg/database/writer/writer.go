@@ -1,8 +1,11 @@
 package writer
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -10,17 +13,25 @@ import (
 
 	gravity_sdk_types_record "github.com/BrobridgeOrg/gravity-sdk/types/record"
 	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/connstr"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database/oraerror"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/logging"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/metrics"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/secret"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/tracing"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/watcher"
 	buffered_input "github.com/cfsghost/buffered-input"
 	"github.com/jmoiron/sqlx"
-	_ "github.com/mattn/go-oci8"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 var (
 	UpdateTemplate = `UPDATE %s SET %s WHERE %s = :primary_val`
 	InsertTemplate = `INSERT INTO %s (%s) VALUES (%s)`
 	DeleteTemplate = `DELETE FROM %s WHERE %s = :primary_val`
+	UpsertTemplate = `MERGE INTO %s USING dual ON ("%s" = :primary_val)%s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)`
 )
 
 var recordDefPool = sync.Pool{
@@ -29,184 +40,505 @@ var recordDefPool = sync.Pool{
 	},
 }
 
-type DatabaseInfo struct {
-	Host     string `json:"host"`
-	Port     int    `json:"port"`
-	Username string `json:"username"`
-	Password string `json:"password"`
-	DbName   string `json:"db_name"`
-	Param    string `json:"param"`
-}
-
 type Writer struct {
-	dbInfo            *DatabaseInfo
+	config            *Config
+	dbInfo            *connstr.Options
+	driver            Driver
 	db                *sqlx.DB
 	commands          chan *DBCommand
 	completionHandler database.CompletionHandler
+	exitHandler       func(error)
 	buffer            *buffered_input.BufferedInput
+	bulkBuffer        *buffered_input.BufferedInput
+	bulkPending       *bulkTracker
+	arrayBinding      bool
+	queue             *CommandQueue
+	retrier           *Retrier
+	appInfo           *AppInfoOptions
+	pool              *PoolOptions
+	timeout           *TimeoutOptions
+	connector         *SessionConnector
+	secretWatcher     *watcher.Watcher
+	lag               *lagTracker
+	heartbeat         *Heartbeat
+	fence             *leaseFence
+	states            *OracleStateBackend
+	credentialMutex   sync.Mutex
+
+	// Batches are no longer written once writer is closing, and in-flight
+	// statements are canceled with ctx after shutdown timeout
+	ctx      context.Context
+	cancel   context.CancelFunc
+	closing  context.Context
+	close    context.CancelFunc
+	inflight sync.RWMutex
 }
 
 func NewWriter() *Writer {
 	writer := &Writer{
-		dbInfo:            &DatabaseInfo{},
 		commands:          make(chan *DBCommand, 2048),
 		completionHandler: func(database.DBCommand) {},
+		exitHandler:       func(error) {},
+		lag:               newLagTracker(),
 	}
 
+	writer.ctx, writer.cancel = context.WithCancel(context.Background())
+	writer.closing, writer.close = context.WithCancel(context.Background())
+
 	// Initializing buffered input
+	viper.SetDefault("bufferInput.chunkCount", 10000)
 	opts := buffered_input.NewOptions()
 	opts.ChunkSize = viper.GetInt("bufferInput.chunkSize")
-	opts.ChunkCount = 10000
+	opts.ChunkCount = viper.GetInt("bufferInput.chunkCount")
 	opts.Timeout = viper.GetDuration("bufferInput.timeout") * time.Millisecond
 	opts.Handler = writer.chunkHandler
 	writer.buffer = buffered_input.NewBufferedInput(opts)
 
+	// Initializing buffered input for bulk loading
+	writer.bulkPending = newBulkTracker()
+	writer.initBulkInput()
+
+	writer.retrier = NewRetrier(writer)
+
 	return writer
 }
 
 func (writer *Writer) Init() error {
 
-	// Initialize data
-	service_name := viper.GetString("database.serviceName")
-	sid := viper.GetString("database.sid")
-	if service_name != "" && sid != "" {
-		log.Error("Only one of serviceName or sid can be used")
-		return nil
+	// Read configuration file
+	config, err := LoadConfig()
+	if err != nil {
+		log.Error(err)
+		return err
 	}
 
-	dbname := ""
-	if service_name != "" {
-		dbname = service_name
+	// Initializing retry policy
+	writer.retrier.Init(config.Retry)
+
+	err = writer.Open(config)
+	if err != nil {
+		log.Error(err)
+		return err
 	}
 
-	if sid != "" {
-		dbname = sid
+	// Credential will be reloaded once its file was changed
+	writer.secretWatcher, err = secret.Watch(credentialKeys, func() {
+		writer.reloadCredential()
+	})
+	if err != nil {
+		log.Error(err)
+		return err
 	}
 
-	// Read configuration file
-	writer.dbInfo.Host = viper.GetString("database.host")
-	writer.dbInfo.Port = viper.GetInt("database.port")
-	writer.dbInfo.Username = viper.GetString("database.username")
-	writer.dbInfo.Password = viper.GetString("database.password")
-	writer.dbInfo.DbName = dbname
-	writer.dbInfo.Param = viper.GetString("database.param")
+	// Initializing disk-backed queue
+	if config.Queue.Enabled {
+		writer.queue = NewCommandQueue(writer)
+		err = writer.queue.Init()
+		if err != nil {
+			log.Error(err)
+			return err
+		}
+	}
+
+	go writer.keepalive()
+	go writer.trackLag()
+	go writer.retrier.Run()
+	go writer.run()
+	return nil
+}
+
+// Start writes commands which were queued before restarting and starts reporting heartbeat, it should
+// be called once this instance is the one which writes, so standby doesn't write anything.
+func (writer *Writer) Start() error {
+
+	if writer.queue != nil {
+		go writer.queue.Run()
+	}
+
+	return writer.startHeartbeat()
+}
+
+// Open connects to database with configuration which was validated by LoadConfig.
+func (writer *Writer) Open(config *Config) error {
+
+	writer.config = config
+	writer.dbInfo = config.Connection
+	writer.pool = config.Pool
+	writer.timeout = config.Timeout
+	writer.appInfo = config.AppInfo
+
+	var err error
+	writer.driver, err = GetDriver(config.Driver)
+	if err != nil {
+		return err
+	}
+
+	// Bulk rows are inserted by multi-row query if driver doesn't support array binding
+	_, writer.arrayBinding = writer.driver.(ArrayBinder)
+
+	connectString, err := writer.dbInfo.GetConnectString()
+	if err != nil {
+		return err
+	}
+
+	err = writer.initWallet()
+	if err != nil {
+		return err
+	}
 
 	log.WithFields(log.Fields{
-		"host":     writer.dbInfo.Host,
-		"port":     writer.dbInfo.Port,
-		"username": writer.dbInfo.Username,
-		"dbname":   writer.dbInfo.DbName,
-		"param":    writer.dbInfo.Param,
+		"driver":        writer.driver.Name(),
+		"arrayBinding":  writer.arrayBinding,
+		"connectString": connectString,
+		"username":      writer.dbInfo.Username,
+		"param":         writer.dbInfo.Param,
+		"tls":           writer.dbInfo.TLS,
+		"externalAuth":  writer.dbInfo.ExternalAuth,
 	}).Info("Connecting to database")
 
-	connStr := fmt.Sprintf(
-		"%s/%s@%s:%d/%s?%s",
-		writer.dbInfo.Username,
-		writer.dbInfo.Password,
-		writer.dbInfo.Host,
-		writer.dbInfo.Port,
-		writer.dbInfo.DbName,
-		writer.dbInfo.Param,
-	)
+	connStr, err := writer.driver.DSN(writer.dbInfo)
+	if err != nil {
+		return err
+	}
 
-	// Open database
-	db, err := sqlx.Open("oci8", connStr)
+	// Every session is initialized by connector
+	statements := append(config.Session.GetStatements(), writer.appInfo.GetStatements()...)
+	connector, err := NewSessionConnector(writer.driver.Name(), connStr, statements)
 	if err != nil {
-		log.Error(err)
 		return err
 	}
 
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(10)
+	// Open database
+	db := sqlx.NewDb(sql.OpenDB(connector), writer.driver.Name())
+
+	writer.initPool(db)
 
 	writer.db = db
+	writer.connector = connector
 
-	if err = writer.setTimeFormatOnSession(); err != nil {
-		log.Error(err)
-		return err
+	return writer.verifySession(config.Session, writer.appInfo)
+}
 
+// Close stops writing, and waits for in-flight batches until shutdown timeout. Commands
+// which were not written are not acknowledged, they will be written again after restart.
+func (writer *Writer) Close() {
+
+	writer.close()
+
+	done := make(chan struct{})
+	go func() {
+		writer.inflight.Lock()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(writer.timeout.Shutdown):
+		log.Warn("Canceling in-flight batches because shutdown timeout was exceeded")
+		writer.cancel()
+
+		select {
+		case <-done:
+		case <-time.After(writer.timeout.Shutdown):
+			log.Error("In-flight batches were not canceled in time")
+		}
 	}
 
-	go writer.run()
-	return nil
+	writer.cancel()
+
+	if writer.secretWatcher != nil {
+		writer.secretWatcher.Close()
+	}
+
+	if writer.heartbeat != nil {
+		writer.heartbeat.Close()
+	}
+
+	if writer.queue != nil {
+		writer.queue.Close()
+	}
+
+	if writer.db != nil {
+		err := writer.db.Close()
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	log.Info("Writer was closed")
+}
+
+// SetExitHandler sets handler which shuts down application once writer cannot keep going.
+func (writer *Writer) SetExitHandler(fn func(error)) {
+	writer.exitHandler = fn
+}
+
+// fail stops writing and shuts down application with error, records which were not acknowledged are
+// written again after restarting.
+func (writer *Writer) fail(err error) {
+	log.Error(err)
+	writer.Abort()
+	writer.exitHandler(err)
+}
+
+// Abort stops writing immediately, in-flight batches are canceled and rolled back rather than
+// waiting for shutdown timeout. It is used once this instance is no longer allowed to write.
+func (writer *Writer) Abort() {
+	writer.close()
+	writer.cancel()
 }
 
 func (writer *Writer) chunkHandler(chunk []interface{}) {
 
+	writer.inflight.RLock()
+	defer writer.inflight.RUnlock()
+
+	if writer.isClosing() {
+		return
+	}
+
 	dbCommands := make([]*DBCommand, 0, len(chunk))
 	for _, request := range chunk {
 		req := request.(*DBCommand)
 		dbCommands = append(dbCommands, req)
 	}
+
+	// Commands of paused tables are held until tables are resumed
+	dbCommands = writer.retrier.Hold(dbCommands)
+
+	// Snapshot rows which came before should be written first
+	writer.flushBulk(dbCommands)
+
 	writer.processData(dbCommands)
 }
 
+func (writer *Writer) initWallet() error {
+
+	if len(writer.dbInfo.WalletDir) == 0 {
+		return nil
+	}
+
+	err := connstr.ValidateWallet(writer.dbInfo.WalletDir)
+	if err != nil {
+		return err
+	}
+
+	// Oracle client loads sqlnet.ora and tnsnames.ora of wallet from TNS_ADMIN
+	if len(os.Getenv("TNS_ADMIN")) == 0 {
+		log.WithFields(log.Fields{
+			"path": writer.dbInfo.WalletDir,
+		}).Info("Setting TNS_ADMIN to wallet directory")
+
+		return os.Setenv("TNS_ADMIN", writer.dbInfo.WalletDir)
+	}
+
+	return nil
+}
+
+func (writer *Writer) execBatch(dbCommands []*DBCommand) (failed *DBCommand, err error) {
+
+	// Transaction is linked to every event of batch
+	contexts := make([]context.Context, 0, len(dbCommands))
+	for _, cmd := range dbCommands {
+		if cmd.queued != nil {
+			cmd.queued.End()
+			cmd.queued = nil
+		}
+
+		contexts = append(contexts, getContext(cmd.Reference))
+	}
+
+	_, span := tracing.StartWithLinks("Writer.execBatch", contexts, attribute.Int("commands", len(dbCommands)))
+	defer func() {
+		tracing.End(span, err)
+	}()
+
+	ctx, cancel := writer.batchContext()
+	defer cancel()
+
+	tx, release, err := writer.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	defer release()
+
+	writer.setAction(ctx, tx, getBatchAction(dbCommands))
+
+	for _, cmd := range dbCommands {
+		err := writer.namedExec(ctx, tx, cmd.QueryStr, cmd.Args)
+		if err != nil {
+			fields := log.Fields{
+				"category": oraerror.GetCategory(err),
+			}
+			if cmd.Record != nil {
+				fields["pkey_field"] = cmd.Record.PrimaryKey
+			}
+
+			log.WithFields(fields).Error(err)
+			log.Error(cmd.QueryStr)
+			log.Error(logging.MaskArgs(cmd.Args, cmd.getBindColumns()))
+			tx.Rollback()
+
+			// The whole batch is retried if it was timed out
+			if ctx.Err() != nil {
+				return nil, err
+			}
+
+			return cmd, err
+		}
+	}
+
+	sequences, err := writer.saveSequences(ctx, tx, dbCommands)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = writer.checkFence(ctx, tx)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	err = tx.Commit()
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if len(sequences) > 0 {
+		writer.states.commitSequences(sequences)
+	}
+
+	writer.lag.committed(dbCommands)
+
+	return nil, nil
+}
+
+func (writer *Writer) namedExec(ctx context.Context, tx *sqlx.Tx, query string, arg interface{}) error {
+
+	ctx, cancel := writer.statementContext(ctx)
+	defer cancel()
+
+	_, err := tx.NamedExecContext(ctx, query, arg)
+
+	return err
+}
+
 func (writer *Writer) processData(dbCommands []*DBCommand) {
+
+	connBackoff := writer.retrier.connectionPolicy.NewBackoff()
+	stmtBackoff := writer.retrier.statementPolicy.NewBackoff()
+
 	// Write to Database
-	for {
-	LOOP:
-		tx, err := writer.db.Beginx()
-		if err != nil {
+	for len(dbCommands) > 0 {
+
+		// Commands will be written again after restart
+		if writer.isClosing() {
+			return
+		}
+
+		failed, err := writer.execBatch(dbCommands)
+		if err == nil {
+			break
+		}
+
+		// Another instance is writing, commands will be written by it
+		if err == ErrFenced {
 			log.Error(err)
-			tx.Rollback()
+			writer.Abort()
+			return
+		}
 
-			<-time.After(time.Second * 5)
+		category := oraerror.GetCategory(err)
+		metrics.DatabaseErrors.WithLabelValues(string(category)).Inc()
 
-			log.WithFields(log.Fields{}).Warn("Retry to write record to database by batch ...")
-			continue
+		// Password might be changed
+		if isCredentialError(err) {
+			writer.reloadCredential()
 		}
 
-		for _, cmd := range dbCommands {
-			_, err := tx.NamedExec(cmd.QueryStr, cmd.Args)
-			if err != nil {
-				log.WithFields(log.Fields{
-					"pkey_field": cmd.Record.PrimaryKey,
-				}).Error(err)
-				log.Error(cmd.QueryStr)
-				log.Error(cmd.Args)
-				tx.Rollback()
-				<-time.After(time.Second * 5)
-				goto LOOP
+		if failed == nil || category == oraerror.CategoryConnectivity {
+			log.WithFields(log.Fields{
+				"category": category,
+			}).Error(err)
 
+			// Nothing can be done without database
+			if !connBackoff.WaitContext(writer.closing) {
+				if writer.isClosing() {
+					continue
+				}
+
+				writer.fail(fmt.Errorf("Exiting because retry attempts of connection were exhausted: %v", err))
+				return
 			}
+
+			log.WithFields(log.Fields{
+				"attempts": connBackoff.Attempts(),
+			}).Warn("Retry to write record to database by batch ...")
+			continue
 		}
-		err = tx.Commit()
 
-		if err != nil {
-			log.Error(err)
-			tx.Rollback()
+		connBackoff.Reset()
 
-			<-time.After(time.Second * 5)
+		// Retrying doesn't help if record itself is invalid
+		if !category.IsPermanent() && stmtBackoff.WaitContext(writer.closing) {
+			log.WithFields(log.Fields{
+				"attempts": stmtBackoff.Attempts(),
+			}).Warn("Retry to write record to database by batch ...")
+			continue
+		}
 
-			log.WithFields(log.Fields{}).Warn("Retry to write record to database by batch ...")
+		if writer.isClosing() {
 			continue
 		}
 
-		break
+		// Give up failed command, and keep going with the rest
+		rest := make([]*DBCommand, 0, len(dbCommands)-1)
+		for _, cmd := range dbCommands {
+			if cmd != failed {
+				rest = append(rest, cmd)
+			}
+		}
+
+		writer.retrier.Exhausted(failed.Table, []*DBCommand{failed}, err)
+		dbCommands = writer.retrier.Hold(rest)
+		stmtBackoff.Reset()
 	}
 
 	for _, cmd := range dbCommands {
-		writer.completionHandler(database.DBCommand(cmd))
-		recordDefPool.Put(cmd.RecordDef)
-		dbCommandPool.Put(cmd)
+		writer.complete(cmd)
 	}
 }
 
-func (writer *Writer) setTimeFormatOnSession() error {
+func (writer *Writer) complete(cmd *DBCommand) {
 
-	sqlStr := fmt.Sprintf(`ALTER SESSION SET NLS_DATE_FORMAT='yyyy-mm-dd hh24:mi:ss'`)
-	_, err := writer.db.Exec(sqlStr)
-	if err != nil {
-		return err
+	if cmd.queued != nil {
+		cmd.queued.End()
+		cmd.queued = nil
 	}
 
-	sqlStr = fmt.Sprintf(`ALTER SESSION SET NLS_TIMESTAMP_FORMAT='yyyy-mm-dd hh24:mi:ss.ff'`)
-	_, err = writer.db.Exec(sqlStr)
-	if err != nil {
-		return err
+	writer.lag.remove(cmd)
+	cmd.EventTime = time.Time{}
+	cmd.ReceivedAt = time.Time{}
+
+	if cmd.queueID > 0 {
+		for _, waiting := range writer.queue.Ack(cmd) {
+			defer writer.complete(waiting)
+		}
 	}
 
-	return nil
+	// No one is waiting for command which was replayed from queue
+	if cmd.Reference != nil {
+		writer.completionHandler(database.DBCommand(cmd))
+	}
+
+	if cmd.RecordDef != nil {
+		recordDefPool.Put(cmd.RecordDef)
+	}
+
+	dbCommandPool.Put(cmd)
 }
 
 func (writer *Writer) run() {
@@ -214,8 +546,49 @@ func (writer *Writer) run() {
 	for {
 		select {
 		case cmd := <-writer.commands:
-			// publish to buffered-input
-			writer.buffer.Push(cmd)
+
+			cmd.Table = cmd.Record.Table
+			cmd.bulk = false
+			cmd.PipelineID = 0
+			cmd.Sequence = 0
+			cmd.EventTime = time.Time{}
+			cmd.ReceivedAt = time.Time{}
+			if ref, ok := cmd.Reference.(database.EventReference); ok {
+				cmd.PipelineID = ref.GetPipelineID()
+				cmd.Sequence = ref.GetSequence()
+				cmd.EventTime = ref.GetEventTime()
+				cmd.ReceivedAt = ref.GetReceivedAt()
+			}
+
+			writer.lag.add(cmd)
+
+			if writer.queue == nil {
+				// publish to buffered-input
+				writer.buffer.Push(cmd)
+				continue
+			}
+
+			// Event is acknowledged once it was written by replaying queue
+			replayed, waiting := writer.queue.Replay(cmd)
+			if waiting {
+				continue
+			}
+
+			if replayed {
+				writer.complete(cmd)
+				continue
+			}
+
+			// Commands will be published to buffered-input by queue
+			for {
+				err := writer.queue.Push(cmd)
+				if err == nil {
+					break
+				}
+
+				log.Error(err)
+				<-time.After(time.Second * 5)
+			}
 		}
 	}
 }
@@ -226,16 +599,39 @@ func (writer *Writer) SetCompletionHandler(fn database.CompletionHandler) {
 
 func (writer *Writer) ProcessData(reference interface{}, record *gravity_sdk_types_record.Record, tables []string) error {
 
+	_, span := tracing.Start(getContext(reference), "Writer.ProcessData",
+		attribute.String("table", record.Table),
+		attribute.String("method", record.Method.String()),
+	)
+
+	var err error
 	switch record.Method {
 	case gravity_sdk_types_record.Method_DELETE:
-		return writer.DeleteRecord(reference, record, tables)
+		err = writer.DeleteRecord(reference, record, tables)
 	case gravity_sdk_types_record.Method_UPDATE:
-		return writer.UpdateRecord(reference, record, tables)
+		err = writer.UpdateRecord(reference, record, tables)
 	case gravity_sdk_types_record.Method_INSERT:
-		return writer.InsertRecord(reference, record, tables)
+		err = writer.InsertRecord(reference, record, tables)
 	}
 
-	return nil
+	tracing.End(span, err)
+
+	return err
+}
+
+// push sends command to be written, time in queue is traced until command was written by batch.
+func (writer *Writer) push(cmd *DBCommand) {
+	_, cmd.queued = tracing.Start(getContext(cmd.Reference), "Writer.commands")
+	writer.commands <- cmd
+}
+
+func getContext(reference interface{}) context.Context {
+
+	if ref, ok := reference.(database.EventReference); ok {
+		return ref.GetContext()
+	}
+
+	return context.Background()
 }
 
 func (writer *Writer) GetDefinition(record *gravity_sdk_types_record.Record) (*gravity_sdk_types_record.RecordDef, error) {
@@ -312,6 +708,21 @@ func (writer *Writer) UpdateRecord(reference interface{}, record *gravity_sdk_ty
 	return nil
 }
 
+func (writer *Writer) UpsertRecord(reference interface{}, record *gravity_sdk_types_record.Record, tables []string) error {
+
+	recordDef, err := writer.GetDefinition(record)
+	if err != nil {
+		return err
+	}
+
+	// Insert directly if no primary key
+	if recordDef.HasPrimary == false {
+		return writer.insert(reference, record, record.Table, recordDef, tables)
+	}
+
+	return writer.upsert(reference, record, record.Table, recordDef, tables)
+}
+
 func (writer *Writer) DeleteRecord(reference interface{}, record *gravity_sdk_types_record.Record, tables []string) error {
 
 	if record.PrimaryKey == "" {
@@ -336,9 +747,10 @@ func (writer *Writer) DeleteRecord(reference interface{}, record *gravity_sdk_ty
 			dbCommand.Args = map[string]interface{}{
 				"primary_val": value,
 			}
+			dbCommand.RecordDef = nil
 			dbCommand.Tables = tables
 
-			writer.commands <- dbCommand
+			writer.push(dbCommand)
 
 			break
 		}
@@ -366,12 +778,53 @@ func (writer *Writer) update(reference interface{}, record *gravity_sdk_types_re
 	dbCommand.RecordDef = recordDef
 	dbCommand.Tables = tables
 
-	writer.commands <- dbCommand
+	writer.push(dbCommand)
 
 	return false, nil
 }
 
-func (writer *Writer) insert(reference interface{}, record *gravity_sdk_types_record.Record, table string, recordDef *gravity_sdk_types_record.RecordDef, tables []string) error {
+func (writer *Writer) upsert(reference interface{}, record *gravity_sdk_types_record.Record, table string, recordDef *gravity_sdk_types_record.RecordDef, tables []string) error {
+
+	// Allocation
+	updates := make([]string, 0, len(recordDef.ColumnDefs))
+	colNames := make([]string, 0, len(recordDef.ColumnDefs)+1)
+	valNames := make([]string, 0, len(recordDef.ColumnDefs)+1)
+
+	colNames = append(colNames, `"`+recordDef.PrimaryColumn+`"`)
+	valNames = append(valNames, ":primary_val")
+
+	// Preparing columns and bindings
+	for _, def := range recordDef.ColumnDefs {
+		updates = append(updates, `"`+def.ColumnName+`" = :`+def.BindingName)
+		colNames = append(colNames, `"`+def.ColumnName+`"`)
+		valNames = append(valNames, `:`+def.BindingName)
+	}
+
+	// Nothing to update if there is primary key only
+	updateStr := ""
+	if len(updates) > 0 {
+		updateStr = " WHEN MATCHED THEN UPDATE SET " + strings.Join(updates, ",")
+	}
+
+	// Preparing SQL string to merge
+	colsStr := strings.Join(colNames, ",")
+	valsStr := strings.Join(valNames, ",")
+	sqlStr := fmt.Sprintf(UpsertTemplate, table, recordDef.PrimaryColumn, updateStr, colsStr, valsStr)
+
+	dbCommand := dbCommandPool.Get().(*DBCommand)
+	dbCommand.Reference = reference
+	dbCommand.Record = record
+	dbCommand.QueryStr = sqlStr
+	dbCommand.Args = recordDef.Values
+	dbCommand.RecordDef = recordDef
+	dbCommand.Tables = tables
+
+	writer.push(dbCommand)
+
+	return nil
+}
+
+func getInsertSQL(table string, recordDef *gravity_sdk_types_record.RecordDef) string {
 
 	paramLength := len(recordDef.ColumnDefs)
 	if recordDef.HasPrimary {
@@ -396,7 +849,13 @@ func (writer *Writer) insert(reference interface{}, record *gravity_sdk_types_re
 	// Preparing SQL string to insert
 	colsStr := strings.Join(colNames, ",")
 	valsStr := strings.Join(valNames, ",")
-	insertStr := fmt.Sprintf(InsertTemplate, table, colsStr, valsStr)
+
+	return fmt.Sprintf(InsertTemplate, table, colsStr, valsStr)
+}
+
+func (writer *Writer) insert(reference interface{}, record *gravity_sdk_types_record.Record, table string, recordDef *gravity_sdk_types_record.RecordDef, tables []string) error {
+
+	insertStr := getInsertSQL(table, recordDef)
 	//	database.db.NamedExec(insertStr, recordDef.Values)
 
 	dbCommand := dbCommandPool.Get().(*DBCommand)
@@ -407,7 +866,7 @@ func (writer *Writer) insert(reference interface{}, record *gravity_sdk_types_re
 	dbCommand.RecordDef = recordDef
 	dbCommand.Tables = tables
 
-	writer.commands <- dbCommand
+	writer.push(dbCommand)
 
 	return nil
 }
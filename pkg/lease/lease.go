@@ -0,0 +1,18 @@
+package lease
+
+import (
+	"time"
+)
+
+// Store grants leases on resources, a lease is held until it was released or expired.
+type Store interface {
+
+	// Acquire takes or renews lease, false will be returned if resource was held by others.
+	Acquire(resource string, holder string, ttl time.Duration) (bool, error)
+	Release(resource string, holder string) error
+
+	// List returns holders of resources which were not expired, resources are filtered by prefix.
+	List(prefix string) (map[string]string, error)
+
+	Close() error
+}
@@ -0,0 +1,159 @@
+package logging
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	modulePath = "github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/"
+	sdkPath    = "github.com/BrobridgeOrg/gravity-sdk/"
+
+	// Expired entries of rate limiter are removed once there are too many
+	maxRateLimitEntries = 1024
+)
+
+type rateLimitEntry struct {
+	loggedAt   time.Time
+	suppressed int
+}
+
+// Formatter filters entries by levels of subsystems and rate limit before they were formatted.
+type Formatter struct {
+	formatter  log.Formatter
+	level      log.Level
+	subsystems map[string]log.Level
+	rateLimit  time.Duration
+	entries    map[string]*rateLimitEntry
+	mutex      sync.Mutex
+}
+
+func NewFormatter(formatter log.Formatter, options *Options) *Formatter {
+	return &Formatter{
+		formatter:  formatter,
+		level:      options.Level,
+		subsystems: options.Subsystems,
+		rateLimit:  options.RateLimit,
+		entries:    make(map[string]*rateLimitEntry),
+	}
+}
+
+// getSubsystem returns name of subsystem by package of caller, e.g. "database" for pkg/database/writer.
+func getSubsystem(entry *log.Entry) string {
+
+	if entry.Caller == nil {
+		return ""
+	}
+
+	function := entry.Caller.Function
+	if strings.HasPrefix(function, sdkPath) {
+		return "gravity"
+	}
+
+	if !strings.HasPrefix(function, modulePath) {
+		return ""
+	}
+
+	name := strings.TrimPrefix(function, modulePath)
+	if i := strings.IndexAny(name, "/."); i != -1 {
+		name = name[:i]
+	}
+
+	return name
+}
+
+func getRateLimitKey(entry *log.Entry) string {
+
+	keys := make([]string, 0, len(entry.Data))
+	for key := range entry.Data {
+		keys = append(keys, key)
+	}
+
+	sort.Strings(keys)
+
+	var b strings.Builder
+	b.WriteString(entry.Level.String())
+	b.WriteString(entry.Message)
+	for _, key := range keys {
+		fmt.Fprintf(&b, " %s=%v", key, entry.Data[key])
+	}
+
+	return b.String()
+}
+
+// allow returns whether entry should be logged, and number of identical entries which were suppressed.
+func (f *Formatter) allow(entry *log.Entry) (bool, int) {
+
+	// Fatal and panic are never suppressed
+	if f.rateLimit == 0 || entry.Level < log.ErrorLevel || entry.Level > log.WarnLevel {
+		return true, 0
+	}
+
+	key := getRateLimitKey(entry)
+
+	f.mutex.Lock()
+	defer f.mutex.Unlock()
+
+	e, ok := f.entries[key]
+	if ok && entry.Time.Sub(e.loggedAt) < f.rateLimit {
+		e.suppressed++
+		return false, 0
+	}
+
+	if len(f.entries) >= maxRateLimitEntries {
+		for k, e := range f.entries {
+			if entry.Time.Sub(e.loggedAt) >= f.rateLimit {
+				delete(f.entries, k)
+			}
+		}
+	}
+
+	suppressed := 0
+	if ok {
+		suppressed = e.suppressed
+	}
+
+	f.entries[key] = &rateLimitEntry{
+		loggedAt: entry.Time,
+	}
+
+	return true, suppressed
+}
+
+func (f *Formatter) Format(entry *log.Entry) ([]byte, error) {
+
+	subsystem := getSubsystem(entry)
+
+	level, ok := f.subsystems[subsystem]
+	if !ok {
+		level = f.level
+	}
+
+	// Nothing is written for entries which were filtered out
+	if entry.Level > level {
+		return nil, nil
+	}
+
+	ok, suppressed := f.allow(entry)
+	if !ok {
+		return nil, nil
+	}
+
+	if len(subsystem) > 0 {
+		entry.Data["subsystem"] = subsystem
+	}
+
+	if suppressed > 0 {
+		entry.Data["suppressed"] = suppressed
+	}
+
+	// Caller is only used to find subsystem
+	entry.Caller = nil
+
+	return f.formatter.Format(entry)
+}
@@ -0,0 +1,101 @@
+package logging
+
+import (
+	"testing"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// recordingFormatter keeps entries which reached it.
+type recordingFormatter struct {
+	entries []*log.Entry
+}
+
+func (f *recordingFormatter) Format(entry *log.Entry) ([]byte, error) {
+	f.entries = append(f.entries, entry)
+	return []byte(entry.Message), nil
+}
+
+func newEntry(level log.Level, message string, at time.Time) *log.Entry {
+	return &log.Entry{
+		Data:    log.Fields{"table": "USERS"},
+		Time:    at,
+		Level:   level,
+		Message: message,
+	}
+}
+
+func TestFormatterRateLimit(t *testing.T) {
+
+	recorder := &recordingFormatter{}
+	f := NewFormatter(recorder, &Options{
+		Level:     log.InfoLevel,
+		RateLimit: time.Minute,
+	})
+
+	now := time.Now()
+
+	// Identical entries in interval are suppressed
+	f.Format(newEntry(log.ErrorLevel, "failed", now))
+	f.Format(newEntry(log.ErrorLevel, "failed", now.Add(time.Second)))
+	f.Format(newEntry(log.ErrorLevel, "failed", now.Add(time.Second*2)))
+
+	// Different message, level or fields are not identical
+	f.Format(newEntry(log.ErrorLevel, "other", now.Add(time.Second)))
+	f.Format(newEntry(log.WarnLevel, "failed", now.Add(time.Second)))
+
+	// Info is never limited
+	f.Format(newEntry(log.InfoLevel, "failed", now.Add(time.Second)))
+	f.Format(newEntry(log.InfoLevel, "failed", now.Add(time.Second)))
+
+	if len(recorder.entries) != 5 {
+		t.Fatalf("expected 5 entries, got %d", len(recorder.entries))
+	}
+
+	// The next one after interval reports suppressed entries
+	f.Format(newEntry(log.ErrorLevel, "failed", now.Add(time.Minute+time.Second)))
+
+	if len(recorder.entries) != 6 {
+		t.Fatalf("expected 6 entries, got %d", len(recorder.entries))
+	}
+
+	last := recorder.entries[5]
+	if last.Data["suppressed"] != 2 {
+		t.Errorf("expected 2 suppressed entries, got %v", last.Data["suppressed"])
+	}
+}
+
+func TestFormatterRateLimitDisabled(t *testing.T) {
+
+	recorder := &recordingFormatter{}
+	f := NewFormatter(recorder, &Options{
+		Level: log.InfoLevel,
+	})
+
+	now := time.Now()
+	for i := 0; i < 3; i++ {
+		f.Format(newEntry(log.ErrorLevel, "failed", now))
+	}
+
+	if len(recorder.entries) != 3 {
+		t.Errorf("expected 3 entries, got %d", len(recorder.entries))
+	}
+}
+
+func TestFormatterLevel(t *testing.T) {
+
+	recorder := &recordingFormatter{}
+	f := NewFormatter(recorder, &Options{
+		Level: log.WarnLevel,
+	})
+
+	data, err := f.Format(newEntry(log.InfoLevel, "ignored", time.Now()))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if data != nil || len(recorder.entries) != 0 {
+		t.Errorf("expected entry below level to be filtered out")
+	}
+}
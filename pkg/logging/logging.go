@@ -0,0 +1,106 @@
+package logging
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/config"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+)
+
+type Options struct {
+	Level      log.Level
+	Format     string
+	Subsystems map[string]log.Level
+
+	// Identical warnings and errors are logged once in the interval, 0 means disabled
+	RateLimit time.Duration
+
+	Mask *MaskOptions
+}
+
+// LoadOptions loads options of logging, all problems are returned at once.
+func LoadOptions() (*Options, error) {
+
+	var problems config.Problems
+
+	// Level was specified by GRAVITY_DEBUG before it was able to be configured
+	level := os.Getenv("GRAVITY_DEBUG")
+	if len(level) == 0 {
+		level = log.InfoLevel.String()
+	}
+
+	viper.SetDefault("log.level", level)
+	viper.SetDefault("log.format", FormatText)
+	viper.SetDefault("log.rateLimit", 60)
+
+	options := &Options{
+		Format:     viper.GetString("log.format"),
+		Subsystems: make(map[string]log.Level),
+		RateLimit:  viper.GetDuration("log.rateLimit") * time.Second,
+		Mask:       LoadMaskOptions(),
+	}
+
+	var err error
+	options.Level, err = log.ParseLevel(viper.GetString("log.level"))
+	if err != nil {
+		problems.Addf("log.level: %v", err)
+	}
+
+	for subsystem, value := range viper.GetStringMapString("log.subsystems") {
+		l, err := log.ParseLevel(value)
+		if err != nil {
+			problems.Addf("log.subsystems.%s: %v", subsystem, err)
+			continue
+		}
+
+		options.Subsystems[strings.ToLower(subsystem)] = l
+	}
+
+	if options.Format != FormatText && options.Format != FormatJSON {
+		problems.Addf("log.format: should be \"%s\" or \"%s\"", FormatText, FormatJSON)
+	}
+
+	if options.RateLimit < 0 {
+		problems.Addf("log.rateLimit: should not be less than 0")
+	}
+
+	problems.Add(options.Mask.Validate())
+
+	return options, problems.Err()
+}
+
+// Init applies options to the standard logger.
+func Init(options *Options) {
+
+	var formatter log.Formatter = &log.TextFormatter{}
+	if options.Format == FormatJSON {
+		formatter = &log.JSONFormatter{}
+	}
+
+	// Entries of every subsystem have to reach formatter, levels are checked by formatter
+	level := options.Level
+	for _, l := range options.Subsystems {
+		if l > level {
+			level = l
+		}
+	}
+
+	log.SetLevel(level)
+
+	// Caller is used to tell which subsystem an entry comes from
+	if len(options.Subsystems) > 0 {
+		log.SetReportCaller(true)
+	}
+
+	log.SetFormatter(NewFormatter(formatter, options))
+
+	SetMaskPolicy(options.Mask)
+}
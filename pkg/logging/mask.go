@@ -0,0 +1,102 @@
+package logging
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/spf13/viper"
+)
+
+const (
+	MaskNone    = "none"
+	MaskColumns = "columns"
+	MaskAll     = "all"
+
+	maskedValue = "***"
+)
+
+type MaskOptions struct {
+	Mode    string
+	Columns []string
+}
+
+func LoadMaskOptions() *MaskOptions {
+
+	viper.SetDefault("log.mask.mode", MaskAll)
+
+	return &MaskOptions{
+		Mode:    viper.GetString("log.mask.mode"),
+		Columns: viper.GetStringSlice("log.mask.columns"),
+	}
+}
+
+func (options *MaskOptions) Validate() error {
+
+	switch options.Mode {
+	case MaskNone, MaskAll:
+	case MaskColumns:
+		if len(options.Columns) == 0 {
+			return fmt.Errorf("log.mask.columns: columns are required by \"%s\" mode", MaskColumns)
+		}
+	default:
+		return fmt.Errorf("log.mask.mode: should be \"%s\", \"%s\" or \"%s\"", MaskNone, MaskColumns, MaskAll)
+	}
+
+	return nil
+}
+
+var (
+	maskMode    = MaskAll
+	maskColumns = make(map[string]bool)
+	maskMutex   sync.RWMutex
+)
+
+// SetMaskPolicy sets which values are redacted by MaskArgs.
+func SetMaskPolicy(options *MaskOptions) {
+
+	columns := make(map[string]bool, len(options.Columns))
+	for _, column := range options.Columns {
+		columns[strings.ToUpper(column)] = true
+	}
+
+	maskMutex.Lock()
+	defer maskMutex.Unlock()
+
+	maskMode = options.Mode
+	maskColumns = columns
+}
+
+// MaskArgs returns a copy of bind values with sensitive values redacted, it should be used
+// whenever records are logged. Columns maps bind names to columns, values of unknown columns
+// are redacted by "columns" mode as well.
+func MaskArgs(args map[string]interface{}, columns map[string]string) map[string]interface{} {
+
+	maskMutex.RLock()
+	defer maskMutex.RUnlock()
+
+	masked := make(map[string]interface{}, len(args))
+	for name, value := range args {
+		if value != nil && isMasked(columns, name) {
+			masked[name] = maskedValue
+			continue
+		}
+
+		masked[name] = value
+	}
+
+	return masked
+}
+
+func isMasked(columns map[string]string, name string) bool {
+
+	switch maskMode {
+	case MaskAll:
+		return true
+	case MaskColumns:
+		column, ok := columns[name]
+		return !ok || maskColumns[strings.ToUpper(column)]
+	}
+
+	return false
+}
@@ -0,0 +1,90 @@
+package logging
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMaskArgs(t *testing.T) {
+
+	args := map[string]interface{}{
+		"primary_val": int64(1),
+		"val_1":       "a@example.com",
+		"val_2":       "Alice",
+		"val_3":       nil,
+		"val_4":       "unknown",
+	}
+
+	columns := map[string]string{
+		"primary_val": "id",
+		"val_1":       "email",
+		"val_2":       "name",
+		"val_3":       "ssn",
+	}
+
+	tests := []struct {
+		mode     string
+		expected map[string]interface{}
+	}{
+		{
+			mode:     MaskNone,
+			expected: args,
+		},
+		{
+			mode: MaskAll,
+			expected: map[string]interface{}{
+				"primary_val": maskedValue,
+				"val_1":       maskedValue,
+				"val_2":       maskedValue,
+				"val_3":       nil,
+				"val_4":       maskedValue,
+			},
+		},
+		{
+			// Columns are matched case-insensitively, and unknown columns are masked
+			mode: MaskColumns,
+			expected: map[string]interface{}{
+				"primary_val": int64(1),
+				"val_1":       maskedValue,
+				"val_2":       "Alice",
+				"val_3":       nil,
+				"val_4":       maskedValue,
+			},
+		},
+	}
+
+	defer SetMaskPolicy(&MaskOptions{
+		Mode: MaskAll,
+	})
+
+	for _, test := range tests {
+		SetMaskPolicy(&MaskOptions{
+			Mode:    test.mode,
+			Columns: []string{"EMAIL", "Ssn"},
+		})
+
+		masked := MaskArgs(args, columns)
+		if !reflect.DeepEqual(masked, test.expected) {
+			t.Errorf("%s: expected %v, got %v", test.mode, test.expected, masked)
+		}
+	}
+
+	if args["val_1"] != "a@example.com" {
+		t.Errorf("expected args not to be modified")
+	}
+}
+
+func TestMaskOptionsValidate(t *testing.T) {
+
+	if err := (&MaskOptions{Mode: MaskColumns}).Validate(); err == nil {
+		t.Errorf("expected error for columns mode without columns")
+	}
+
+	if err := (&MaskOptions{Mode: "some"}).Validate(); err == nil {
+		t.Errorf("expected error for unknown mode")
+	}
+
+	if err := (&MaskOptions{Mode: MaskNone}).Validate(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+}
@@ -0,0 +1,135 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+const namespace = "gravity_transmitter_oracle"
+
+var (
+	InitialLoadRowsWritten = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "initial_load",
+		Name:      "rows_written",
+		Help:      "Number of snapshot records which were written to database.",
+	}, []string{"collection"})
+
+	InitialLoadRowsEstimated = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "initial_load",
+		Name:      "rows_estimated",
+		Help:      "Estimated number of snapshot records.",
+	}, []string{"collection"})
+
+	InitialLoadElapsedSeconds = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "initial_load",
+		Name:      "elapsed_seconds",
+		Help:      "Elapsed time of initial load.",
+	}, []string{"collection"})
+
+	InitialLoadCompleted = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "initial_load",
+		Name:      "completed",
+		Help:      "Whether initial load is completed.",
+	}, []string{"collection"})
+)
+
+var (
+	InflightMessages = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "flow_control",
+		Name:      "inflight_messages",
+		Help:      "Number of messages which are not acknowledged yet.",
+	})
+
+	FlowControlPaused = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "flow_control",
+		Name:      "paused",
+		Help:      "Whether fetching from gravity is paused.",
+	})
+
+	FlowControlPauses = prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "flow_control",
+		Name:      "pauses_total",
+		Help:      "Number of times fetching from gravity was paused.",
+	})
+)
+
+var (
+	PausedTables = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "retry",
+		Name:      "paused_tables",
+		Help:      "Whether table is paused because retry attempts were exhausted.",
+	}, []string{"table"})
+
+	DeadLetters = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "retry",
+		Name:      "dead_letters_total",
+		Help:      "Number of commands which were dead-lettered.",
+	}, []string{"table"})
+)
+
+var (
+	DatabaseErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Subsystem: "database",
+		Name:      "errors_total",
+		Help:      "Number of errors returned by database.",
+	}, []string{"category"})
+)
+
+var (
+	ReplicationLagSeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "replication",
+		Name:      "lag_seconds",
+		Help:      "Time from event to being committed to database.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+	}, []string{"table"})
+
+	ReplicationLatencySeconds = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+		Namespace: namespace,
+		Subsystem: "replication",
+		Name:      "latency_seconds",
+		Help:      "Time from receiving event to being committed to database.",
+		Buckets:   prometheus.ExponentialBuckets(0.01, 2, 16),
+	}, []string{"table"})
+
+	OldestUnackedEventAgeSeconds = prometheus.NewGauge(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Subsystem: "replication",
+		Name:      "oldest_unacked_event_age_seconds",
+		Help:      "Age of the oldest event which is not written to database yet.",
+	})
+)
+
+func init() {
+	prometheus.MustRegister(
+		InitialLoadRowsWritten,
+		InitialLoadRowsEstimated,
+		InitialLoadElapsedSeconds,
+		InitialLoadCompleted,
+		InflightMessages,
+		FlowControlPaused,
+		FlowControlPauses,
+		PausedTables,
+		DeadLetters,
+		DatabaseErrors,
+		ReplicationLagSeconds,
+		ReplicationLatencySeconds,
+		OldestUnackedEventAgeSeconds,
+	)
+}
+
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
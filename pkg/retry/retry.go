@@ -0,0 +1,180 @@
+package retry
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"time"
+
+	"github.com/spf13/viper"
+)
+
+type Action string
+
+const (
+	ActionDeadLetter Action = "deadletter"
+	ActionPause      Action = "pause"
+	ActionExit       Action = "exit"
+)
+
+// Policy decides how long to wait before next attempt and how many attempts can be made.
+type Policy struct {
+	InitialDelay time.Duration
+	Multiplier   float64
+	Jitter       float64
+	MaxDelay     time.Duration
+
+	// Zero means retrying forever
+	MaxAttempts int
+}
+
+var (
+	// Retrying forever because nothing can be written without connection
+	DefaultConnectionPolicy = Policy{
+		InitialDelay: time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+		MaxDelay:     time.Minute,
+		MaxAttempts:  0,
+	}
+
+	DefaultStatementPolicy = Policy{
+		InitialDelay: time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+		MaxDelay:     time.Second * 30,
+		MaxAttempts:  10,
+	}
+)
+
+// LoadPolicy loads policy from configuration with specific prefix (e.g. retry.connection).
+func LoadPolicy(prefix string, defaults Policy) *Policy {
+
+	viper.SetDefault(prefix+".initialDelay", defaults.InitialDelay.Milliseconds())
+	viper.SetDefault(prefix+".multiplier", defaults.Multiplier)
+	viper.SetDefault(prefix+".jitter", defaults.Jitter)
+	viper.SetDefault(prefix+".maxDelay", defaults.MaxDelay.Milliseconds())
+	viper.SetDefault(prefix+".maxAttempts", defaults.MaxAttempts)
+
+	return &Policy{
+		InitialDelay: viper.GetDuration(prefix+".initialDelay") * time.Millisecond,
+		Multiplier:   viper.GetFloat64(prefix + ".multiplier"),
+		Jitter:       viper.GetFloat64(prefix + ".jitter"),
+		MaxDelay:     viper.GetDuration(prefix+".maxDelay") * time.Millisecond,
+		MaxAttempts:  viper.GetInt(prefix + ".maxAttempts"),
+	}
+}
+
+func (policy *Policy) Validate() error {
+
+	if policy.InitialDelay <= 0 {
+		return fmt.Errorf("initialDelay should be higher than 0")
+	}
+
+	if policy.Multiplier < 1 {
+		return fmt.Errorf("multiplier should not be less than 1")
+	}
+
+	if policy.Jitter < 0 || policy.Jitter > 1 {
+		return fmt.Errorf("jitter should be between 0 and 1")
+	}
+
+	if policy.MaxDelay < policy.InitialDelay {
+		return fmt.Errorf("maxDelay should not be less than initialDelay")
+	}
+
+	if policy.MaxAttempts < 0 {
+		return fmt.Errorf("maxAttempts should not be less than 0")
+	}
+
+	return nil
+}
+
+func (policy *Policy) NewBackoff() *Backoff {
+	return &Backoff{
+		policy: policy,
+	}
+}
+
+// Backoff keeps state of retrying for an operation.
+type Backoff struct {
+	policy   *Policy
+	attempts int
+	delay    time.Duration
+}
+
+func (b *Backoff) Attempts() int {
+	return b.attempts
+}
+
+func (b *Backoff) Reset() {
+	b.attempts = 0
+	b.delay = 0
+}
+
+// Next returns delay before next attempt, false will be returned if attempts were exhausted.
+func (b *Backoff) Next() (time.Duration, bool) {
+
+	b.attempts++
+
+	if b.policy.MaxAttempts > 0 && b.attempts >= b.policy.MaxAttempts {
+		return 0, false
+	}
+
+	if b.delay == 0 {
+		b.delay = b.policy.InitialDelay
+	} else {
+		b.delay = time.Duration(float64(b.delay) * b.policy.Multiplier)
+	}
+
+	if b.delay > b.policy.MaxDelay {
+		b.delay = b.policy.MaxDelay
+	}
+
+	// Randomize delay to avoid retrying at the same time
+	delay := b.delay
+	if b.policy.Jitter > 0 {
+		delta := float64(delay) * b.policy.Jitter
+		delay = time.Duration(float64(delay) - delta + rand.Float64()*delta*2)
+	}
+
+	return delay, true
+}
+
+// Wait sleeps before next attempt, false will be returned if attempts were exhausted.
+func (b *Backoff) Wait() bool {
+	return b.WaitContext(context.Background())
+}
+
+// WaitContext is the same as Wait, but false will be returned as well once context was done.
+func (b *Backoff) WaitContext(ctx context.Context) bool {
+
+	delay, ok := b.Next()
+	if !ok {
+		return false
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// GetAction returns action which should be taken once attempts were exhausted.
+func GetAction() (Action, error) {
+
+	viper.SetDefault("retry.onExhausted", string(ActionPause))
+
+	action := Action(viper.GetString("retry.onExhausted"))
+	switch action {
+	case ActionDeadLetter, ActionPause, ActionExit:
+		return action, nil
+	}
+
+	return "", fmt.Errorf("Unknown action for exhausted retries: %s", action)
+}
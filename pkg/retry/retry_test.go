@@ -0,0 +1,163 @@
+package retry
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestBackoffAttempts(t *testing.T) {
+
+	// Attempts include the first try, so retries are one less than maxAttempts
+	cases := []struct {
+		maxAttempts int
+		retries     int
+	}{
+		{maxAttempts: 1, retries: 0},
+		{maxAttempts: 2, retries: 1},
+		{maxAttempts: 5, retries: 4},
+	}
+
+	for _, c := range cases {
+		policy := &Policy{
+			InitialDelay: time.Millisecond,
+			Multiplier:   2,
+			MaxDelay:     time.Second,
+			MaxAttempts:  c.maxAttempts,
+		}
+
+		b := policy.NewBackoff()
+
+		retries := 0
+		for {
+			_, ok := b.Next()
+			if !ok {
+				break
+			}
+
+			retries++
+		}
+
+		if retries != c.retries {
+			t.Errorf("maxAttempts %d: expected %d retries, got %d", c.maxAttempts, c.retries, retries)
+		}
+
+		if b.Attempts() != c.maxAttempts {
+			t.Errorf("maxAttempts %d: expected %d attempts, got %d", c.maxAttempts, c.maxAttempts, b.Attempts())
+		}
+
+		// Attempts start over after reset
+		b.Reset()
+		if _, ok := b.Next(); !ok && c.maxAttempts > 1 {
+			t.Errorf("maxAttempts %d: expected retry after reset", c.maxAttempts)
+		}
+	}
+}
+
+func TestBackoffRetriesForever(t *testing.T) {
+
+	policy := &Policy{
+		InitialDelay: time.Millisecond,
+		Multiplier:   2,
+		MaxDelay:     time.Second,
+		MaxAttempts:  0,
+	}
+
+	b := policy.NewBackoff()
+	for i := 0; i < 100; i++ {
+		if _, ok := b.Next(); !ok {
+			t.Fatalf("expected retrying forever, stopped after %d attempts", b.Attempts())
+		}
+	}
+}
+
+func TestBackoffDelay(t *testing.T) {
+
+	cases := []struct {
+		name     string
+		policy   Policy
+		expected []time.Duration
+	}{
+		{
+			name: "exponential",
+			policy: Policy{
+				InitialDelay: time.Second,
+				Multiplier:   2,
+				MaxDelay:     time.Minute,
+			},
+			expected: []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second},
+		},
+		{
+			name: "capped",
+			policy: Policy{
+				InitialDelay: time.Second,
+				Multiplier:   3,
+				MaxDelay:     5 * time.Second,
+			},
+			expected: []time.Duration{time.Second, 3 * time.Second, 5 * time.Second, 5 * time.Second},
+		},
+		{
+			name: "constant",
+			policy: Policy{
+				InitialDelay: time.Second,
+				Multiplier:   1,
+				MaxDelay:     time.Second,
+			},
+			expected: []time.Duration{time.Second, time.Second, time.Second},
+		},
+	}
+
+	for _, c := range cases {
+		b := c.policy.NewBackoff()
+		for i, expected := range c.expected {
+			delay, ok := b.Next()
+			if !ok {
+				t.Fatalf("%s: expected attempt %d to be allowed", c.name, i+1)
+			}
+
+			if delay != expected {
+				t.Errorf("%s: expected delay %v of attempt %d, got %v", c.name, expected, i+1, delay)
+			}
+		}
+	}
+}
+
+func TestBackoffJitter(t *testing.T) {
+
+	policy := &Policy{
+		InitialDelay: time.Second,
+		Multiplier:   2,
+		Jitter:       0.2,
+		MaxDelay:     4 * time.Second,
+	}
+
+	// Delay is randomized around the capped delay
+	b := policy.NewBackoff()
+	for i := 0; i < 10; i++ {
+		delay, _ := b.Next()
+		if delay > 4*time.Second*12/10 {
+			t.Errorf("expected delay to be capped with jitter, got %v", delay)
+		}
+	}
+
+	delay, _ := policy.NewBackoff().Next()
+	if delay < time.Second*8/10 || delay > time.Second*12/10 {
+		t.Errorf("expected delay within 20%% of 1s, got %v", delay)
+	}
+}
+
+func TestBackoffWaitContext(t *testing.T) {
+
+	policy := &Policy{
+		InitialDelay: time.Hour,
+		Multiplier:   2,
+		MaxDelay:     time.Hour,
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	if policy.NewBackoff().WaitContext(ctx) {
+		t.Errorf("expected waiting to stop once context was done")
+	}
+}
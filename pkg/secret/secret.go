@@ -0,0 +1,48 @@
+package secret
+
+import (
+	"fmt"
+	"io/ioutil"
+	"strings"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/watcher"
+	"github.com/spf13/viper"
+)
+
+// GetFile returns path of file which secret is loaded from.
+func GetFile(key string) string {
+	return viper.GetString(key + "File")
+}
+
+// Get returns secret which is read from file if "<key>File" was set, otherwise from configuration.
+func Get(key string) (string, error) {
+
+	filename := GetFile(key)
+	if len(filename) == 0 {
+		return viper.GetString(key), nil
+	}
+
+	data, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return "", fmt.Errorf("Failed to read %s from file: %v", key, err)
+	}
+
+	// Newline is usually added at the end of file
+	return strings.TrimRight(string(data), "\r\n"), nil
+}
+
+// Watch watches files of secrets for specific keys, nil will be returned if no secret is read from file.
+func Watch(keys []string, handler func()) (*watcher.Watcher, error) {
+
+	files := make([]string, 0, len(keys))
+	for _, key := range keys {
+		filename := GetFile(key)
+		if len(filename) == 0 {
+			continue
+		}
+
+		files = append(files, filename)
+	}
+
+	return watcher.Watch(files, handler)
+}
@@ -0,0 +1,140 @@
+package state
+
+import (
+	broton "github.com/BrobridgeOrg/broton"
+)
+
+// Stores are named as the same as state store of gravity SDK, so states which were saved by
+// earlier versions are still available
+const (
+	pipelineStoreName = "gravity_state_store"
+	stateStoreName    = "oracle_transmitter"
+	pipelineColumn    = "pipeline"
+)
+
+// LocalBackend keeps states in local directory.
+type LocalBackend struct {
+	engine    *broton.Broton
+	pipelines *broton.Store
+	states    *broton.Store
+}
+
+func NewLocalBackend(path string, columns []string) (*LocalBackend, error) {
+
+	options := broton.NewOptions()
+	options.DatabasePath = path
+	engine, err := broton.NewBroton(options)
+	if err != nil {
+		return nil, err
+	}
+
+	backend := &LocalBackend{
+		engine: engine,
+	}
+
+	backend.pipelines, err = engine.GetStore(pipelineStoreName)
+	if err != nil {
+		engine.Close()
+		return nil, err
+	}
+
+	err = backend.pipelines.RegisterColumns([]string{pipelineColumn})
+	if err != nil {
+		engine.Close()
+		return nil, err
+	}
+
+	backend.states, err = engine.GetStore(stateStoreName)
+	if err != nil {
+		engine.Close()
+		return nil, err
+	}
+
+	err = backend.states.RegisterColumns(columns)
+	if err != nil {
+		engine.Close()
+		return nil, err
+	}
+
+	return backend, nil
+}
+
+func (backend *LocalBackend) GetSequence(pipelineID uint64) (uint64, error) {
+	return backend.pipelines.GetUint64(pipelineColumn, broton.Uint64ToBytes(pipelineID))
+}
+
+func (backend *LocalBackend) PutSequence(pipelineID uint64, sequence uint64) error {
+	return backend.pipelines.PutUint64(pipelineColumn, broton.Uint64ToBytes(pipelineID), sequence)
+}
+
+func (backend *LocalBackend) DeleteSequence(pipelineID uint64) error {
+	return backend.pipelines.Delete(pipelineColumn, broton.Uint64ToBytes(pipelineID))
+}
+
+func (backend *LocalBackend) ListSequences() (map[uint64]uint64, error) {
+
+	sequences := make(map[uint64]uint64)
+	err := backend.pipelines.List(pipelineColumn, []byte{}, func(key []byte, value []byte) bool {
+		sequences[broton.BytesToUint64(key)] = broton.BytesToUint64(value)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return sequences, nil
+}
+
+func (backend *LocalBackend) Get(column string, key string) ([]byte, error) {
+
+	data, err := backend.states.GetBytes(column, []byte(key))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	return data, nil
+}
+
+func (backend *LocalBackend) Put(column string, key string, data []byte) error {
+	return backend.states.Put(column, []byte(key), data)
+}
+
+func (backend *LocalBackend) Delete(column string, key string) error {
+	return backend.states.Delete(column, []byte(key))
+}
+
+func (backend *LocalBackend) List(column string) (map[string][]byte, error) {
+
+	states := make(map[string][]byte)
+	err := backend.states.List(column, []byte{}, func(key []byte, value []byte) bool {
+		states[string(key)] = append([]byte{}, value...)
+		return true
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return states, nil
+}
+
+func (backend *LocalBackend) Flush() error {
+
+	cf, err := backend.pipelines.GetColumnFamailyHandle(pipelineColumn)
+	if err != nil {
+		return err
+	}
+
+	_, err = cf.Db.AsyncFlush()
+
+	return err
+}
+
+// Close flushes states to disk.
+func (backend *LocalBackend) Close() error {
+	backend.engine.Close()
+	return nil
+}
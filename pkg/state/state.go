@@ -0,0 +1,20 @@
+package state
+
+// Backend persists states of subscriber, including sequences of pipelines which
+// were written and states of transmitter (e.g. initial load).
+type Backend interface {
+	GetSequence(pipelineID uint64) (uint64, error)
+	PutSequence(pipelineID uint64, sequence uint64) error
+	DeleteSequence(pipelineID uint64) error
+	ListSequences() (map[uint64]uint64, error)
+
+	// States of transmitter are grouped by column, nil will be returned if not found
+	Get(column string, key string) ([]byte, error)
+	Put(column string, key string, data []byte) error
+	Delete(column string, key string) error
+	List(column string) (map[string][]byte, error)
+
+	// Flush makes sure states were saved
+	Flush() error
+	Close() error
+}
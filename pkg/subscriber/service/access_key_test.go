@@ -0,0 +1,68 @@
+package subscriber
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/BrobridgeOrg/gravity-sdk/core/keyring"
+	gravity_subscriber "github.com/BrobridgeOrg/gravity-sdk/subscriber"
+	"github.com/spf13/viper"
+)
+
+func TestReloadAccessKey(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	keyFile := filepath.Join(dir, "accessKey")
+	err = ioutil.WriteFile(keyFile, []byte("new\n"), 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	viper.Set("subscriber.accessKeyFile", keyFile)
+	defer viper.Set("subscriber.accessKeyFile", "")
+
+	options := gravity_subscriber.NewOptions()
+	options.Key = keyring.NewKey("transmitter", "old")
+	old := options.Key
+
+	subscriber := &Subscriber{
+		config: &Config{
+			AppID:     "transmitter",
+			AccessKey: "old",
+		},
+		options: options,
+	}
+
+	subscriber.reloadAccessKey()
+
+	expected := sha256.Sum256([]byte("new"))
+	if !bytes.Equal(options.Key.Encryption().GetKey(), expected[:]) {
+		t.Errorf("expected requests to be sent with new access key")
+	}
+
+	if options.Key.GetAppID() != "transmitter" {
+		t.Errorf("expected app ID to be kept, got %s", options.Key.GetAppID())
+	}
+
+	// Key which is being used by requests in progress is not modified
+	unchanged := sha256.Sum256([]byte("old"))
+	if !bytes.Equal(old.Encryption().GetKey(), unchanged[:]) {
+		t.Errorf("expected previous key not to be modified")
+	}
+
+	// Nothing is changed if access key is the same
+	current := options.Key
+	subscriber.reloadAccessKey()
+	if options.Key != current {
+		t.Errorf("expected key not to be replaced again")
+	}
+}
@@ -0,0 +1,201 @@
+package subscriber
+
+import (
+	"encoding/json"
+	"io/ioutil"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/config"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/retry"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/secret"
+	"github.com/spf13/viper"
+)
+
+// Config is the configuration of subscriber.
+type Config struct {
+	Host           string
+	Domain         string
+	AppID          string
+	AccessKey      string
+	SubscriberID   string
+	SubscriberName string
+	WorkerCount    int
+	ChunkSize      int
+	Verbose        bool
+
+	// Flow control
+	MaxInflight    int
+	ResumeInflight int
+
+	// Range of pipelines, -1 means the last one
+	PipelineStart int64
+	PipelineEnd   int64
+
+	// Field of records which carries time of event, for measuring lag
+	EventTimeField string
+
+	InitialLoadEnabled      bool
+	InitialLoadOmittedCount uint64
+	InitialLoadDeferredPath string
+
+	State        *StateOptions
+	Coordination *CoordinationOptions
+	RuleFile     string
+	Rules        *RuleConfig
+	RetryPolicy  *retry.Policy
+}
+
+// LoadConfig loads and validates configuration of subscriber and rules, all problems are returned at once.
+func LoadConfig() (*Config, error) {
+
+	var problems config.Problems
+
+	viper.SetDefault("gravity.domain", "gravity")
+	viper.SetDefault("subscriber.appID", "anonymous")
+	viper.SetDefault("subscriber.accessKey", "")
+	viper.SetDefault("subscriber.workerCount", 4)
+	viper.SetDefault("subscriber.chunkSize", 2048)
+	viper.SetDefault("subscriber.maxInflight", 20000)
+	viper.SetDefault("subscriber.resumeInflight", viper.GetInt("subscriber.maxInflight")/2)
+	viper.SetDefault("subscriber.pipelineStart", 0)
+	viper.SetDefault("subscriber.pipelineEnd", -1)
+	viper.SetDefault("initialLoad.deferredPath", "./deferred")
+
+	cfg := &Config{
+		Host:                    viper.GetString("gravity.host"),
+		Domain:                  viper.GetString("gravity.domain"),
+		AppID:                   viper.GetString("subscriber.appID"),
+		SubscriberID:            viper.GetString("subscriber.subscriberID"),
+		SubscriberName:          viper.GetString("subscriber.subscriberName"),
+		State:                   LoadStateOptions(),
+		Coordination:            LoadCoordinationOptions(),
+		WorkerCount:             viper.GetInt("subscriber.workerCount"),
+		ChunkSize:               viper.GetInt("subscriber.chunkSize"),
+		Verbose:                 viper.GetBool("subscriber.verbose"),
+		MaxInflight:             viper.GetInt("subscriber.maxInflight"),
+		ResumeInflight:          viper.GetInt("subscriber.resumeInflight"),
+		PipelineStart:           viper.GetInt64("subscriber.pipelineStart"),
+		PipelineEnd:             viper.GetInt64("subscriber.pipelineEnd"),
+		EventTimeField:          viper.GetString("subscriber.eventTimeField"),
+		InitialLoadEnabled:      viper.GetBool("initialLoad.enabled"),
+		InitialLoadOmittedCount: viper.GetUint64("initialLoad.omittedCount"),
+		InitialLoadDeferredPath: viper.GetString("initialLoad.deferredPath"),
+		RuleFile:                viper.GetString("rules.subscription"),
+
+		// Records which cannot be prepared are retried by statement policy
+		RetryPolicy: retry.LoadPolicy("retry.statement", retry.DefaultStatementPolicy),
+	}
+
+	accessKey, err := secret.Get("subscriber.accessKey")
+	if err != nil {
+		problems.Addf("subscriber.accessKey: %v", err)
+	}
+
+	cfg.AccessKey = accessKey
+
+	// Gravity
+	if len(cfg.Host) == 0 {
+		problems.Addf("gravity.host: host is required")
+	}
+
+	if len(cfg.Domain) == 0 {
+		problems.Addf("gravity.domain: domain is required")
+	}
+
+	// Subscriber
+	if len(cfg.SubscriberID) == 0 {
+		problems.Addf("subscriber.subscriberID: subscriber ID is required")
+	}
+
+	problems.Add(cfg.State.Validate())
+	problems.Add(cfg.Coordination.Validate(cfg.State))
+
+	if cfg.WorkerCount <= 0 {
+		problems.Addf("subscriber.workerCount: should be greater than 0")
+	}
+
+	if cfg.ChunkSize <= 0 {
+		problems.Addf("subscriber.chunkSize: should be greater than 0")
+	}
+
+	if cfg.MaxInflight <= 0 {
+		problems.Addf("subscriber.maxInflight: should be greater than 0")
+	}
+
+	if cfg.ResumeInflight < 0 || cfg.ResumeInflight > cfg.MaxInflight {
+		problems.Addf("subscriber.resumeInflight: should be between 0 and subscriber.maxInflight")
+	}
+
+	if cfg.PipelineStart < 0 {
+		problems.Addf("subscriber.pipelineStart: should be higher than -1")
+	}
+
+	if cfg.PipelineStart > cfg.PipelineEnd && cfg.PipelineEnd != -1 {
+		problems.Addf("subscriber.pipelineStart: should be less than subscriber.pipelineEnd")
+	}
+
+	err = cfg.RetryPolicy.Validate()
+	if err != nil {
+		problems.Addf("retry.statement: %v", err)
+	}
+
+	// Rules
+	if len(cfg.RuleFile) == 0 {
+		problems.Addf("rules.subscription: path is required")
+		return cfg, problems.Err()
+	}
+
+	cfg.Rules, err = LoadRuleFile(cfg.RuleFile)
+	problems.AddWithPrefix(cfg.RuleFile, err)
+
+	return cfg, problems.Err()
+}
+
+// LoadRuleFile loads and validates rules.
+func LoadRuleFile(filename string) (*RuleConfig, error) {
+
+	byteValue, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse config
+	var config RuleConfig
+	err = json.Unmarshal(byteValue, &config)
+	if err != nil {
+		return nil, err
+	}
+
+	err = validateRules(&config)
+	if err != nil {
+		return nil, err
+	}
+
+	return &config, nil
+}
+
+func validateRules(rules *RuleConfig) error {
+
+	var problems config.Problems
+
+	if len(rules.Subscriptions) == 0 {
+		problems.Addf("subscriptions: no collection was subscribed")
+	}
+
+	for collection, tables := range rules.Subscriptions {
+
+		if len(tables) == 0 {
+			problems.Addf("subscriptions: no table for collection \"%s\"", collection)
+			continue
+		}
+
+		for _, table := range tables {
+			if len(table) == 0 {
+				problems.Addf("subscriptions: empty table name for collection \"%s\"", collection)
+			}
+		}
+	}
+
+	problems.Add(rules.validateInitialLoad())
+
+	return problems.Err()
+}
@@ -0,0 +1,332 @@
+package subscriber
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/lease"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+)
+
+const (
+	instanceLeasePrefix = "instance/"
+	pipelineLeasePrefix = "pipeline/"
+)
+
+type CoordinationOptions struct {
+	Enabled    bool
+	Table      string
+	InstanceID string
+	LeaseTTL   time.Duration
+}
+
+func LoadCoordinationOptions() *CoordinationOptions {
+
+	hostname, _ := os.Hostname()
+
+	viper.SetDefault("coordination.table", "GRAVITY_LEASE")
+	viper.SetDefault("coordination.instanceID", hostname)
+	viper.SetDefault("coordination.leaseTTL", 30)
+
+	return &CoordinationOptions{
+		Enabled:    viper.GetBool("coordination.enabled"),
+		Table:      viper.GetString("coordination.table"),
+		InstanceID: viper.GetString("coordination.instanceID"),
+		LeaseTTL:   viper.GetDuration("coordination.leaseTTL") * time.Second,
+	}
+}
+
+func (options *CoordinationOptions) Validate(stateOptions *StateOptions) error {
+
+	if !options.Enabled {
+		return nil
+	}
+
+	if len(options.Table) == 0 {
+		return fmt.Errorf("coordination.table: table is required")
+	}
+
+	if len(options.InstanceID) == 0 {
+		return fmt.Errorf("coordination.instanceID: instance ID is required")
+	}
+
+	if options.LeaseTTL < time.Second*3 {
+		return fmt.Errorf("coordination.leaseTTL: should not be less than 3")
+	}
+
+	// Pipelines are moved between instances, so their states should be shared
+	if stateOptions.Backend != StateBackendOracle {
+		return fmt.Errorf("coordination: oracle state backend is required")
+	}
+
+	return nil
+}
+
+// Coordinator splits pipelines among transmitters by leases. Every instance holds a lease of
+// its own and leases of pipelines which were assigned to it.
+type Coordinator struct {
+	subscriber *Subscriber
+	store      lease.Store
+	options    *CoordinationOptions
+	count      uint64
+	owned      map[uint64]bool
+	handover   map[uint64]bool
+	renewedAt  time.Time
+	mutex      sync.Mutex
+	stopOnce   sync.Once
+	closed     chan struct{}
+	done       chan struct{}
+
+	subscribe func([]uint64) error
+	exit      func(error)
+}
+
+func NewCoordinator(subscriber *Subscriber, store lease.Store, options *CoordinationOptions) *Coordinator {
+	return &Coordinator{
+		subscriber: subscriber,
+		store:      store,
+		options:    options,
+		owned:      make(map[uint64]bool),
+		handover:   make(map[uint64]bool),
+		closed:     make(chan struct{}),
+		done:       make(chan struct{}),
+		subscribe: func(pipelines []uint64) error {
+
+			// Collections of SDK are read by pipelines being initialized, they are not changed by reloading rules meanwhile
+			subscriber.setupMutex.Lock()
+			defer subscriber.setupMutex.Unlock()
+
+			return subscriber.subscriber.SubscribeToPipelines(pipelines)
+		},
+		exit: subscriber.app.Exit,
+	}
+}
+
+func getPipelineResource(pipelineID uint64) string {
+	return pipelineLeasePrefix + strconv.FormatUint(pipelineID, 10)
+}
+
+// getAssignment returns pipelines which should be handled by this instance.
+func (c *Coordinator) getAssignment() (map[uint64]bool, error) {
+
+	leases, err := c.store.List(instanceLeasePrefix)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]string, 0, len(leases))
+	for resource := range leases {
+		instances = append(instances, strings.TrimPrefix(resource, instanceLeasePrefix))
+	}
+
+	sort.Strings(instances)
+
+	index := sort.SearchStrings(instances, c.options.InstanceID)
+	if index == len(instances) || instances[index] != c.options.InstanceID {
+		return nil, fmt.Errorf("Lease of instance \"%s\" was lost", c.options.InstanceID)
+	}
+
+	assignment := make(map[uint64]bool)
+	for pipelineID := uint64(index); pipelineID < c.count; pipelineID += uint64(len(instances)) {
+		assignment[pipelineID] = true
+	}
+
+	return assignment, nil
+}
+
+// Start registers instance and subscribes to pipelines which were assigned.
+func (c *Coordinator) Start() error {
+
+	count, err := c.subscriber.subscriber.GetPipelineCount()
+	if err != nil {
+		return err
+	}
+
+	c.count = count
+
+	log.WithFields(log.Fields{
+		"instanceID": c.options.InstanceID,
+		"pipelines":  count,
+	}).Info("Registering instance for pipeline coordination")
+
+	err = c.rebalance()
+	if err != nil {
+		return err
+	}
+
+	go c.run()
+
+	return nil
+}
+
+func (c *Coordinator) run() {
+
+	defer close(c.done)
+
+	// Leases are renewed several times before expired
+	ticker := time.NewTicker(c.options.LeaseTTL / 3)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			err := c.rebalance()
+			if err == nil {
+				continue
+			}
+
+			log.Error(err)
+
+			// Pipelines might be taken by others once leases were expired
+			c.mutex.Lock()
+			expired := time.Since(c.renewedAt) > c.options.LeaseTTL
+			c.mutex.Unlock()
+
+			if expired {
+				c.exit(fmt.Errorf("Leases were not renewed in time: %v", err))
+				return
+			}
+		case <-c.closed:
+			return
+		}
+	}
+}
+
+// rebalance renews leases, and takes pipelines which were assigned to this instance once
+// they were not held by others.
+func (c *Coordinator) rebalance() error {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	startedAt := time.Now()
+
+	ok, err := c.store.Acquire(instanceLeasePrefix+c.options.InstanceID, c.options.InstanceID, c.options.LeaseTTL)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return fmt.Errorf("Instance ID \"%s\" is used by another transmitter", c.options.InstanceID)
+	}
+
+	for pipelineID := range c.owned {
+		ok, err := c.store.Acquire(getPipelineResource(pipelineID), c.options.InstanceID, c.options.LeaseTTL)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			c.exit(fmt.Errorf("Lease of pipeline %d was taken by another transmitter", pipelineID))
+			return nil
+		}
+	}
+
+	c.renewedAt = startedAt
+
+	assignment, err := c.getAssignment()
+	if err != nil {
+		return err
+	}
+
+	// Pipelines cannot be unsubscribed, so they are kept until this instance was restarted, and
+	// the instance which they were assigned to waits for their leases to be released or expired
+	for pipelineID := range c.owned {
+		if assignment[pipelineID] {
+			delete(c.handover, pipelineID)
+			continue
+		}
+
+		if c.handover[pipelineID] {
+			continue
+		}
+
+		log.WithFields(log.Fields{
+			"pipeline": pipelineID,
+		}).Info("Pipeline was assigned to another transmitter, it will be handed over after restarting")
+
+		c.handover[pipelineID] = true
+	}
+
+	// Pipelines are taken once previous holders released them or were crashed
+	acquired := make([]uint64, 0)
+	for pipelineID := range assignment {
+		if c.owned[pipelineID] {
+			continue
+		}
+
+		ok, err := c.store.Acquire(getPipelineResource(pipelineID), c.options.InstanceID, c.options.LeaseTTL)
+		if err != nil {
+			return err
+		}
+
+		if ok {
+			acquired = append(acquired, pipelineID)
+		}
+	}
+
+	if len(acquired) == 0 {
+		return nil
+	}
+
+	sort.Slice(acquired, func(i, j int) bool {
+		return acquired[i] < acquired[j]
+	})
+
+	log.WithFields(log.Fields{
+		"pipelines": acquired,
+	}).Info("Subscribing to pipelines which were assigned")
+
+	err = c.subscribe(acquired)
+	if err != nil {
+		return err
+	}
+
+	for _, pipelineID := range acquired {
+		c.owned[pipelineID] = true
+	}
+
+	return nil
+}
+
+// Stop stops renewing leases and taking pipelines.
+func (c *Coordinator) Stop() {
+	c.stopOnce.Do(func() {
+		close(c.closed)
+		<-c.done
+	})
+}
+
+// Close releases leases, it should be called after states were saved so that
+// the next holder continues from the last sequence.
+func (c *Coordinator) Close() {
+
+	c.Stop()
+	c.release()
+
+	c.store.Close()
+}
+
+func (c *Coordinator) release() {
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	for pipelineID := range c.owned {
+		err := c.store.Release(getPipelineResource(pipelineID), c.options.InstanceID)
+		if err != nil {
+			log.Error(err)
+		}
+	}
+
+	err := c.store.Release(instanceLeasePrefix+c.options.InstanceID, c.options.InstanceID)
+	if err != nil {
+		log.Error(err)
+	}
+}
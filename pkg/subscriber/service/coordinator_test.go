@@ -0,0 +1,235 @@
+package subscriber
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+)
+
+// memoryLeaseStore grants leases in memory, leases never expire unless expire was called.
+type memoryLeaseStore struct {
+	leases map[string]string
+}
+
+func newMemoryLeaseStore() *memoryLeaseStore {
+	return &memoryLeaseStore{
+		leases: make(map[string]string),
+	}
+}
+
+func (store *memoryLeaseStore) Acquire(resource string, holder string, ttl time.Duration) (bool, error) {
+
+	current, ok := store.leases[resource]
+	if ok && current != holder {
+		return false, nil
+	}
+
+	store.leases[resource] = holder
+
+	return true, nil
+}
+
+func (store *memoryLeaseStore) Release(resource string, holder string) error {
+
+	if store.leases[resource] == holder {
+		delete(store.leases, resource)
+	}
+
+	return nil
+}
+
+func (store *memoryLeaseStore) List(prefix string) (map[string]string, error) {
+
+	leases := make(map[string]string)
+	for resource, holder := range store.leases {
+		if strings.HasPrefix(resource, prefix) {
+			leases[resource] = holder
+		}
+	}
+
+	return leases, nil
+}
+
+func (store *memoryLeaseStore) Close() error {
+	return nil
+}
+
+// expire drops all leases of holder as if it was crashed.
+func (store *memoryLeaseStore) expire(holder string) {
+	for resource, h := range store.leases {
+		if h == holder {
+			delete(store.leases, resource)
+		}
+	}
+}
+
+type testCoordinator struct {
+	*Coordinator
+	subscribed []uint64
+	exited     error
+}
+
+func newTestCoordinator(store *memoryLeaseStore, instanceID string, count uint64) *testCoordinator {
+
+	tc := &testCoordinator{}
+	tc.Coordinator = &Coordinator{
+		store: store,
+		options: &CoordinationOptions{
+			InstanceID: instanceID,
+			LeaseTTL:   time.Second * 30,
+		},
+		count:    count,
+		owned:    make(map[uint64]bool),
+		handover: make(map[uint64]bool),
+		subscribe: func(pipelines []uint64) error {
+			tc.subscribed = append(tc.subscribed, pipelines...)
+			return nil
+		},
+		exit: func(err error) {
+			tc.exited = err
+		},
+	}
+
+	return tc
+}
+
+func (tc *testCoordinator) getOwned() []uint64 {
+
+	pipelines := make([]uint64, 0, len(tc.owned))
+	for pipelineID := range tc.owned {
+		pipelines = append(pipelines, pipelineID)
+	}
+
+	sort.Slice(pipelines, func(i, j int) bool {
+		return pipelines[i] < pipelines[j]
+	})
+
+	return pipelines
+}
+
+func (tc *testCoordinator) mustRebalance(t *testing.T) {
+
+	t.Helper()
+
+	err := tc.rebalance()
+	if err != nil {
+		t.Fatalf("%s: %v", tc.options.InstanceID, err)
+	}
+
+	if tc.exited != nil {
+		t.Fatalf("%s: expected not to exit, got %v", tc.options.InstanceID, tc.exited)
+	}
+}
+
+func TestGetAssignment(t *testing.T) {
+
+	store := newMemoryLeaseStore()
+	for _, instanceID := range []string{"c", "a", "b"} {
+		store.Acquire(instanceLeasePrefix+instanceID, instanceID, time.Second)
+	}
+
+	// Other resources are not instances
+	store.Acquire(getPipelineResource(0), "a", time.Second)
+
+	expected := map[string]map[uint64]bool{
+		"a": {0: true, 3: true, 6: true},
+		"b": {1: true, 4: true},
+		"c": {2: true, 5: true},
+	}
+
+	for instanceID, pipelines := range expected {
+		assignment, err := newTestCoordinator(store, instanceID, 7).getAssignment()
+		if err != nil {
+			t.Fatalf("%s: %v", instanceID, err)
+		}
+
+		if !reflect.DeepEqual(assignment, pipelines) {
+			t.Errorf("%s: expected %v, got %v", instanceID, pipelines, assignment)
+		}
+	}
+
+	// Instance without lease has no assignment
+	_, err := newTestCoordinator(store, "d", 7).getAssignment()
+	if err == nil {
+		t.Errorf("d: expected error for instance without lease")
+	}
+}
+
+func TestRebalance(t *testing.T) {
+
+	store := newMemoryLeaseStore()
+
+	a := newTestCoordinator(store, "a", 4)
+	a.mustRebalance(t)
+
+	if owned := a.getOwned(); !reflect.DeepEqual(owned, []uint64{0, 1, 2, 3}) {
+		t.Fatalf("a: expected to own all pipelines, got %v", owned)
+	}
+
+	// New instance waits for pipelines which are still held, and nobody exits
+	b := newTestCoordinator(store, "b", 4)
+	b.mustRebalance(t)
+	a.mustRebalance(t)
+
+	if owned := b.getOwned(); len(owned) != 0 {
+		t.Fatalf("b: expected to own nothing, got %v", owned)
+	}
+
+	if owned := a.getOwned(); len(owned) != 4 {
+		t.Fatalf("a: expected to keep all pipelines, got %v", owned)
+	}
+
+	if !a.handover[1] || !a.handover[3] || a.handover[0] {
+		t.Errorf("a: expected pipelines 1 and 3 to be handed over, got %v", a.handover)
+	}
+
+	// Released pipelines are taken by the instance which they were assigned to
+	a.release()
+	b.mustRebalance(t)
+
+	if owned := b.getOwned(); !reflect.DeepEqual(owned, []uint64{0, 1, 2, 3}) {
+		t.Fatalf("b: expected to take all pipelines after a was gone, got %v", owned)
+	}
+
+	// Restarted instance takes nothing until leases were expired
+	a = newTestCoordinator(store, "a", 4)
+	a.mustRebalance(t)
+	b.mustRebalance(t)
+
+	if owned := a.getOwned(); len(owned) != 0 {
+		t.Fatalf("a: expected to own nothing, got %v", owned)
+	}
+
+	store.expire("b")
+	a.mustRebalance(t)
+
+	if owned := a.getOwned(); !reflect.DeepEqual(owned, []uint64{0, 1, 2, 3}) {
+		t.Fatalf("a: expected to take all pipelines after b was crashed, got %v", owned)
+	}
+
+	if !reflect.DeepEqual(a.subscribed, []uint64{0, 1, 2, 3}) {
+		t.Errorf("a: expected to subscribe to all pipelines, got %v", a.subscribed)
+	}
+}
+
+func TestRebalanceLostLease(t *testing.T) {
+
+	store := newMemoryLeaseStore()
+
+	a := newTestCoordinator(store, "a", 2)
+	a.mustRebalance(t)
+
+	// Lease was expired and taken by another instance
+	store.leases[getPipelineResource(1)] = "b"
+
+	err := a.rebalance()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if a.exited == nil {
+		t.Errorf("a: expected to exit once lease of pipeline was lost")
+	}
+}
@@ -0,0 +1,191 @@
+package subscriber
+
+import (
+	"bytes"
+	"encoding/gob"
+	"sync"
+	"time"
+
+	broton "github.com/BrobridgeOrg/broton"
+	gravity_sdk_types_record "github.com/BrobridgeOrg/gravity-sdk/types/record"
+	log "github.com/sirupsen/logrus"
+)
+
+// DeferredEvent is what being persisted for an event which is held until shadow table was swapped
+type DeferredEvent struct {
+	Table     string
+	Record    []byte
+	EventTime time.Time
+	id        uint64
+}
+
+// DeferredQueue persists deferred events on disk in order of arrival for each table.
+type DeferredQueue struct {
+	path    string
+	store   *broton.Store
+	lastID  uint64
+	pending map[string]uint64
+	cursors map[string]uint64
+	mutex   sync.Mutex
+}
+
+func NewDeferredQueue(path string) *DeferredQueue {
+	return &DeferredQueue{
+		path:    path,
+		pending: make(map[string]uint64),
+		cursors: make(map[string]uint64),
+	}
+}
+
+func (dq *DeferredQueue) Init() error {
+
+	log.WithFields(log.Fields{
+		"path": dq.path,
+	}).Info("Initializing deferred queue")
+
+	options := broton.NewOptions()
+	options.DatabasePath = dq.path
+	bt, err := broton.NewBroton(options)
+	if err != nil {
+		return err
+	}
+
+	store, err := bt.GetStore("deferred")
+	if err != nil {
+		return err
+	}
+
+	err = store.RegisterColumns([]string{"events"})
+	if err != nil {
+		return err
+	}
+
+	dq.store = store
+
+	// Events were left in queue since last time, they will be applied again
+	return store.List("events", []byte(""), func(key []byte, value []byte) bool {
+
+		table, id := parseDeferredKey(key)
+		dq.pending[table] = id
+
+		if dq.lastID < id {
+			dq.lastID = id
+		}
+
+		return true
+	})
+}
+
+func getDeferredKey(table string, id uint64) []byte {
+	return append([]byte(table+"\x00"), broton.Uint64ToBytes(id)...)
+}
+
+func parseDeferredKey(key []byte) (string, uint64) {
+	sep := bytes.IndexByte(key, 0)
+	return string(key[:sep]), broton.BytesToUint64(key[sep+1:])
+}
+
+// Push saves record for table, its ID is assigned in order of arrival.
+func (dq *DeferredQueue) Push(record *gravity_sdk_types_record.Record, eventTime time.Time) error {
+
+	data, err := gravity_sdk_types_record.Marshal(record)
+	if err != nil {
+		return err
+	}
+
+	var buf bytes.Buffer
+	err = gob.NewEncoder(&buf).Encode(&DeferredEvent{
+		Table:     record.Table,
+		Record:    data,
+		EventTime: eventTime,
+	})
+	if err != nil {
+		return err
+	}
+
+	dq.mutex.Lock()
+	defer dq.mutex.Unlock()
+
+	id := dq.lastID + 1
+	err = dq.store.Put("events", getDeferredKey(record.Table, id), buf.Bytes())
+	if err != nil {
+		return err
+	}
+
+	dq.lastID = id
+	dq.pending[record.Table] = id
+
+	return nil
+}
+
+// Next returns events of table which were not returned yet.
+func (dq *DeferredQueue) Next(table string, limit int) ([]*DeferredEvent, error) {
+
+	dq.mutex.Lock()
+	defer dq.mutex.Unlock()
+
+	prefix := []byte(table + "\x00")
+	events := make([]*DeferredEvent, 0)
+
+	var err error
+	e := dq.store.List("events", getDeferredKey(table, dq.cursors[table]+1), func(key []byte, value []byte) bool {
+
+		if !bytes.HasPrefix(key, prefix) {
+			return false
+		}
+
+		var event DeferredEvent
+		err = gob.NewDecoder(bytes.NewReader(value)).Decode(&event)
+		if err != nil {
+			return false
+		}
+
+		_, event.id = parseDeferredKey(key)
+		events = append(events, &event)
+
+		return len(events) < limit
+	})
+	if e != nil {
+		return nil, e
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	if len(events) > 0 {
+		dq.cursors[table] = events[len(events)-1].id
+	}
+
+	return events, nil
+}
+
+// HasPending returns true if table has events not returned yet.
+func (dq *DeferredQueue) HasPending(table string) bool {
+
+	dq.mutex.Lock()
+	defer dq.mutex.Unlock()
+
+	return dq.cursors[table] < dq.pending[table]
+}
+
+// GetPendingTables returns tables which have events not returned yet.
+func (dq *DeferredQueue) GetPendingTables() []string {
+
+	dq.mutex.Lock()
+	defer dq.mutex.Unlock()
+
+	tables := make([]string, 0)
+	for table, id := range dq.pending {
+		if dq.cursors[table] < id {
+			tables = append(tables, table)
+		}
+	}
+
+	return tables
+}
+
+// Delete removes event once it was written to database.
+func (dq *DeferredQueue) Delete(table string, id uint64) error {
+	return dq.store.Delete("events", getDeferredKey(table, id))
+}
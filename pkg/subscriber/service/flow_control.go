@@ -0,0 +1,86 @@
+package subscriber
+
+import (
+	"sync"
+
+	gravity_subscriber "github.com/BrobridgeOrg/gravity-sdk/subscriber"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+// FlowControl blocks message handler if there are too many messages are not acknowledged.
+// Blocked handler stops subscriber from fetching more messages from gravity.
+type FlowControl struct {
+	limit     int
+	threshold int
+	inflight  map[*gravity_subscriber.Message]struct{}
+	paused    bool
+	mutex     sync.Mutex
+	cond      *sync.Cond
+}
+
+func NewFlowControl(limit int, threshold int) *FlowControl {
+
+	fc := &FlowControl{
+		limit:     limit,
+		threshold: threshold,
+		inflight:  make(map[*gravity_subscriber.Message]struct{}),
+	}
+
+	fc.cond = sync.NewCond(&fc.mutex)
+
+	return fc
+}
+
+func (fc *FlowControl) Acquire(msg *gravity_subscriber.Message) {
+
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if fc.limit > 0 && len(fc.inflight) >= fc.limit {
+
+		fc.paused = true
+
+		log.WithFields(log.Fields{
+			"inflight": len(fc.inflight),
+			"limit":    fc.limit,
+		}).Warn("Too many messages in flight, pausing fetching from gravity")
+
+		metrics.FlowControlPaused.Set(1)
+		metrics.FlowControlPauses.Inc()
+
+		for fc.paused {
+			fc.cond.Wait()
+		}
+
+		log.WithFields(log.Fields{
+			"inflight": len(fc.inflight),
+		}).Info("Writer was drained, resuming fetching from gravity")
+
+		metrics.FlowControlPaused.Set(0)
+	}
+
+	fc.inflight[msg] = struct{}{}
+
+	metrics.InflightMessages.Set(float64(len(fc.inflight)))
+}
+
+// Release is called when message is not in flight anymore, it is safe to be called more than once.
+func (fc *FlowControl) Release(msg *gravity_subscriber.Message) {
+
+	fc.mutex.Lock()
+	defer fc.mutex.Unlock()
+
+	if _, ok := fc.inflight[msg]; !ok {
+		return
+	}
+
+	delete(fc.inflight, msg)
+
+	metrics.InflightMessages.Set(float64(len(fc.inflight)))
+
+	if fc.paused && len(fc.inflight) <= fc.threshold {
+		fc.paused = false
+		fc.cond.Broadcast()
+	}
+}
@@ -0,0 +1,636 @@
+package subscriber
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	gravity_sdk_types_record "github.com/BrobridgeOrg/gravity-sdk/types/record"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/state"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// Period of time without snapshot records from a pipeline which is regarded as drained
+	SnapshotSettleTime = time.Second * 3
+
+	// Pipeline which was expected to deliver snapshot is regarded as empty if no snapshot record came in this period
+	SnapshotStartTimeout = time.Minute
+
+	// Deferred events being read from disk at once
+	DeferredReadLimit = 1000
+)
+
+type WriteMode int32
+
+const (
+	WriteModeInsert WriteMode = iota
+	WriteModeUpsert
+	WriteModeBulk
+)
+
+type InitialLoadState struct {
+	Strategy  InitialLoadStrategy `json:"strategy"`
+	StartedAt time.Time           `json:"startedAt"`
+}
+
+type TableLoad struct {
+	Table     string
+	Shadow    string
+	Rule      *InitialLoadRule
+	Resumed   bool
+	Completed bool
+	Swapped   bool
+	Pipelines map[uint64]struct{}
+}
+
+type PipelineLoad struct {
+	ID        uint64
+	Expected  bool
+	Received  bool
+	Fetched   bool
+	Pending   int
+	UpdatedAt time.Time
+}
+
+type InitialLoader struct {
+	subscriber *Subscriber
+	store      state.Backend
+	tables     map[string]*TableLoad
+	pipelines  map[uint64]*PipelineLoad
+	progress   map[string]*CollectionProgress
+	deferred   *DeferredQueue
+	mutex      sync.Mutex
+}
+
+func NewInitialLoader(subscriber *Subscriber, store state.Backend, deferredPath string) *InitialLoader {
+	return &InitialLoader{
+		subscriber: subscriber,
+		store:      store,
+		deferred:   NewDeferredQueue(deferredPath),
+		tables:     make(map[string]*TableLoad),
+		pipelines:  make(map[uint64]*PipelineLoad),
+		progress:   make(map[string]*CollectionProgress),
+	}
+}
+
+func (il *InitialLoader) Init() error {
+
+	// Events which were deferred since last time
+	if _, err := os.Stat(il.deferred.path); os.IsNotExist(err) {
+		return nil
+	}
+
+	return il.initDeferredQueue()
+}
+
+func (il *InitialLoader) initDeferredQueue() error {
+
+	if il.deferred.store != nil {
+		return nil
+	}
+
+	return il.deferred.Init()
+}
+
+func (il *InitialLoader) loadState(table string) (*InitialLoadState, error) {
+
+	data, err := il.store.Get("initial_load", table)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var state InitialLoadState
+	err = json.Unmarshal(data, &state)
+	if err != nil {
+		return nil, err
+	}
+
+	return &state, nil
+}
+
+func (il *InitialLoader) saveState(table string, state *InitialLoadState) error {
+
+	data, err := json.Marshal(state)
+	if err != nil {
+		return err
+	}
+
+	return il.store.Put("initial_load", table, data)
+}
+
+func (il *InitialLoader) beginTable(table string, rule *InitialLoadRule) (*TableLoad, error) {
+
+	// Initial load which was interrupted before
+	state, err := il.loadState(table)
+	if err != nil {
+		return nil, err
+	}
+
+	tl := &TableLoad{
+		Table:     table,
+		Rule:      rule,
+		Resumed:   state != nil,
+		Pipelines: make(map[uint64]struct{}),
+	}
+
+	writer := il.subscriber.app.GetWriter()
+
+	if tl.Resumed {
+		log.WithFields(log.Fields{
+			"table":     table,
+			"strategy":  state.Strategy,
+			"startedAt": state.StartedAt,
+		}).Warn("Resuming initial load which was interrupted")
+
+		if rule.Strategy == InitialLoadStrategyAppend && len(rule.PrimaryKey) == 0 {
+			log.WithFields(log.Fields{
+				"table": table,
+			}).Warn("No primary key for resumed initial load, records might be duplicated")
+		}
+	} else {
+		log.WithFields(log.Fields{
+			"table":    table,
+			"strategy": rule.Strategy,
+		}).Info("Starting initial load")
+	}
+
+	switch rule.Strategy {
+	case InitialLoadStrategyTruncateFirst:
+
+		// Snapshot is delivered from the beginning again, records are kept only if they will be merged
+		if tl.Resumed && len(rule.PrimaryKey) > 0 {
+			break
+		}
+
+		log.WithFields(log.Fields{
+			"table": table,
+		}).Warn("Truncating table before initial load")
+
+		err := writer.Truncate(table)
+		if err != nil {
+			return nil, err
+		}
+
+	case InitialLoadStrategyShadow:
+
+		// Snapshot records will be loaded into shadow table, it is cloned again unless records will be merged
+		shadow, err := writer.PrepareShadowTable(table, rule.Shadow, tl.Resumed && len(rule.PrimaryKey) > 0)
+		if err != nil {
+			return nil, err
+		}
+
+		log.WithFields(log.Fields{
+			"table":  table,
+			"shadow": shadow,
+		}).Info("Loading snapshot into shadow table")
+
+		tl.Shadow = shadow
+	}
+
+	if tl.isBulk() {
+		err := writer.PrepareBulkLoad(tl.getTarget(), rule.Bulk)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if tl.Resumed {
+		return tl, nil
+	}
+
+	// Keep state until initial load is completed
+	err = il.saveState(table, &InitialLoadState{
+		Strategy:  rule.Strategy,
+		StartedAt: time.Now(),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return tl, nil
+}
+
+// Prepare is called before writing a snapshot record to the table, it returns where and how the record should be written.
+func (il *InitialLoader) Prepare(pipelineID uint64, table string, rule *InitialLoadRule) (string, WriteMode, error) {
+
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+
+	tl, ok := il.tables[table]
+	if !ok {
+		t, err := il.beginTable(table, rule)
+		if err != nil {
+			return table, WriteModeInsert, err
+		}
+
+		tl = t
+		il.tables[table] = tl
+	} else if tl.Completed {
+
+		// Pipeline which started its snapshot late
+		log.WithFields(log.Fields{
+			"table":    table,
+			"pipeline": pipelineID,
+		}).Warn("Received snapshot records after initial load was completed")
+
+		err := il.saveState(table, &InitialLoadState{
+			Strategy:  rule.Strategy,
+			StartedAt: time.Now(),
+		})
+		if err != nil {
+			return table, WriteModeInsert, err
+		}
+
+		tl.Completed = false
+
+		// Shadow table is in use already
+		if tl.Swapped {
+			tl.Shadow = ""
+		}
+	}
+
+	tl.Pipelines[pipelineID] = struct{}{}
+
+	target := tl.getTarget()
+
+	switch {
+	case rule.Strategy == InitialLoadStrategyUpsert:
+		return target, WriteModeUpsert, nil
+	case tl.Resumed && len(rule.PrimaryKey) > 0:
+		// Records which were written already should be overwritten
+		return target, WriteModeUpsert, nil
+	case tl.isBulk():
+		return target, WriteModeBulk, nil
+	}
+
+	return target, WriteModeInsert, nil
+}
+
+// Defer saves record of event on disk if table is being loaded into shadow table or snapshot for shadow table is expected, it is applied after swapping.
+func (il *InitialLoader) Defer(ref *Reference, record *gravity_sdk_types_record.Record, rule *InitialLoadRule) (bool, error) {
+
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+
+	tl, ok := il.tables[record.Table]
+	switch {
+	case il.deferred.HasPending(record.Table):
+		// Events are applied in order
+	case ok && !tl.Completed:
+		if len(tl.Shadow) == 0 {
+			return false, nil
+		}
+	case rule.Strategy == InitialLoadStrategyShadow && il.isSnapshotExpected():
+		// Snapshot of table is not started yet
+	default:
+		return false, nil
+	}
+
+	err := il.initDeferredQueue()
+	if err != nil {
+		return false, err
+	}
+
+	err = il.deferred.Push(record, ref.EventTime)
+	if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// Applied is called once deferred event was written to database.
+func (il *InitialLoader) Applied(ref *Reference) {
+
+	err := il.deferred.Delete(ref.deferredTable, ref.deferredID)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"table": ref.deferredTable,
+		}).Error(err)
+	}
+}
+
+func (tl *TableLoad) getTarget() string {
+
+	if len(tl.Shadow) > 0 {
+		return tl.Shadow
+	}
+
+	return tl.Table
+}
+
+func (tl *TableLoad) isBulk() bool {
+	return tl.Rule.Bulk != nil && tl.Rule.Bulk.Enabled
+}
+
+func (il *InitialLoader) getPipeline(pipelineID uint64) *PipelineLoad {
+
+	pl, ok := il.pipelines[pipelineID]
+	if !ok {
+		pl = &PipelineLoad{
+			ID: pipelineID,
+		}
+		il.pipelines[pipelineID] = pl
+	}
+
+	return pl
+}
+
+// Expect marks pipeline is going to deliver snapshot because it has no state.
+func (il *InitialLoader) Expect(pipelineID uint64) {
+
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+
+	pl := il.getPipeline(pipelineID)
+	pl.Expected = true
+	pl.UpdatedAt = time.Now()
+}
+
+func (il *InitialLoader) Received(pipelineID uint64) {
+
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+
+	pl := il.getPipeline(pipelineID)
+	pl.Received = true
+	pl.Pending++
+	pl.UpdatedAt = time.Now()
+}
+
+func (il *InitialLoader) Written(pipelineID uint64, collection string) {
+
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+
+	pl, ok := il.pipelines[pipelineID]
+	if !ok {
+		return
+	}
+
+	pl.Pending--
+
+	il.written(pipelineID, collection)
+}
+
+// Fetched marks pipeline has no more snapshot records to be pulled from server.
+func (il *InitialLoader) Fetched(pipelineID uint64) {
+
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+
+	pl, ok := il.pipelines[pipelineID]
+	if !ok || pl.Fetched {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"pipeline": pipelineID,
+	}).Info("Snapshot of pipeline was fetched")
+
+	pl.Fetched = true
+}
+
+func (il *InitialLoader) isPipelineCompleted(pipelineID uint64) bool {
+
+	pl, ok := il.pipelines[pipelineID]
+	if !ok {
+		return true
+	}
+
+	if !pl.Fetched || pl.Pending > 0 {
+		return false
+	}
+
+	return time.Since(pl.UpdatedAt) >= SnapshotSettleTime
+}
+
+// isSnapshotExpected returns true if any pipeline has not delivered its snapshot yet.
+func (il *InitialLoader) isSnapshotExpected() bool {
+
+	for _, pl := range il.pipelines {
+
+		if !pl.Expected || il.isPipelineCompleted(pl.ID) {
+			continue
+		}
+
+		// Snapshot of empty pipeline has no records, and it never updates sequence
+		if !pl.Received && time.Since(pl.UpdatedAt) >= SnapshotStartTimeout {
+			continue
+		}
+
+		return true
+	}
+
+	return false
+}
+
+func (il *InitialLoader) complete(tl *TableLoad) error {
+
+	writer := il.subscriber.app.GetWriter()
+
+	// Restore table settings and rebuild indexes before table is in use
+	if tl.isBulk() && !tl.Swapped {
+
+		log.WithFields(log.Fields{
+			"table": tl.getTarget(),
+		}).Info("Finishing bulk load")
+
+		err := writer.FinishBulkLoad(tl.getTarget(), tl.Rule.Bulk)
+		if err != nil {
+			return err
+		}
+	}
+
+	if len(tl.Shadow) > 0 && !tl.Swapped {
+
+		log.WithFields(log.Fields{
+			"table":  tl.Table,
+			"shadow": tl.Shadow,
+		}).Info("Swapping shadow table")
+
+		err := writer.SwapShadowTable(tl.Table, tl.Shadow, tl.Rule.Shadow)
+		if err != nil {
+			return err
+		}
+
+		tl.Swapped = true
+	}
+
+	il.mutex.Lock()
+
+	err := il.applyDeferred(tl.Table)
+	if err != nil {
+		il.mutex.Unlock()
+		return err
+	}
+
+	defer il.mutex.Unlock()
+
+	err = il.store.Delete("initial_load", tl.Table)
+	if err != nil {
+		return err
+	}
+
+	tl.Completed = true
+
+	log.WithFields(log.Fields{
+		"table":    tl.Table,
+		"strategy": tl.Rule.Strategy,
+	}).Info("Initial load completed")
+
+	return nil
+}
+
+func (il *InitialLoader) getCompletedTables() []*TableLoad {
+
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+
+	tables := make([]*TableLoad, 0)
+	for _, tl := range il.tables {
+
+		if tl.Completed {
+			continue
+		}
+
+		completed := true
+		for pipelineID := range tl.Pipelines {
+			if !il.isPipelineCompleted(pipelineID) {
+				completed = false
+				break
+			}
+		}
+
+		if completed {
+			tables = append(tables, tl)
+		}
+	}
+
+	return tables
+}
+
+// applyDeferred writes deferred events of table to database. Mutex is locked by caller, and it is released while writing events, events keep being deferred until no more events left.
+func (il *InitialLoader) applyDeferred(table string) error {
+
+	if il.deferred.store == nil {
+		return nil
+	}
+
+	writer := il.subscriber.app.GetWriter()
+
+	for {
+		events, err := il.deferred.Next(table, DeferredReadLimit)
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		il.mutex.Unlock()
+
+		log.WithFields(log.Fields{
+			"table": table,
+			"count": len(events),
+		}).Info("Applying events which were deferred during initial load")
+
+		for _, event := range events {
+
+			var record gravity_sdk_types_record.Record
+			err := gravity_sdk_types_record.Unmarshal(event.Record, &record)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"table": table,
+				}).Error(err)
+				il.deferred.Delete(table, event.id)
+				continue
+			}
+
+			// Sequence is not updated by deferred events, it was updated once they were deferred
+			ref := &Reference{
+				Context:       context.Background(),
+				EventTime:     event.EventTime,
+				deferredTable: table,
+				deferredID:    event.id,
+			}
+
+			tables := []string{table}
+			backoff := il.subscriber.retryPolicy.NewBackoff()
+			for {
+				err := writer.ProcessData(ref, &record, tables)
+				if err == nil {
+					break
+				}
+
+				log.Error(err)
+
+				if !backoff.Wait() {
+					writer.Reject(ref, &record, tables, err)
+					break
+				}
+			}
+		}
+
+		il.mutex.Lock()
+	}
+}
+
+// getDeferredTables returns tables which have deferred events but no initial load in progress.
+func (il *InitialLoader) getDeferredTables() []string {
+
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+
+	if il.deferred.store == nil || il.isSnapshotExpected() {
+		return nil
+	}
+
+	tables := make([]string, 0)
+	for _, table := range il.deferred.GetPendingTables() {
+		if tl, ok := il.tables[table]; ok && !tl.Completed {
+			continue
+		}
+
+		tables = append(tables, table)
+	}
+
+	return tables
+}
+
+func (il *InitialLoader) check() {
+
+	// Snapshot was expected but nothing was loaded into shadow table
+	for _, table := range il.getDeferredTables() {
+		il.mutex.Lock()
+		err := il.applyDeferred(table)
+		il.mutex.Unlock()
+		if err != nil {
+			log.WithFields(log.Fields{
+				"table": table,
+			}).Error(err)
+		}
+	}
+
+	for _, tl := range il.getCompletedTables() {
+		err := il.complete(tl)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"table": tl.Table,
+			}).Error(err)
+		}
+	}
+}
+
+func (il *InitialLoader) Run() {
+
+	for {
+		<-time.After(time.Second)
+		il.check()
+		il.checkProgress()
+	}
+}
@@ -0,0 +1,279 @@
+package subscriber
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/metrics"
+	log "github.com/sirupsen/logrus"
+)
+
+var (
+	// Interval of reporting initial load progress
+	ProgressReportInterval = time.Second * 10
+)
+
+type CollectionProgress struct {
+	Collection string            `json:"collection"`
+	StartedAt  time.Time         `json:"startedAt"`
+	Elapsed    time.Duration     `json:"elapsed"`
+	Estimated  uint64            `json:"estimated"`
+	Pipelines  map[uint64]uint64 `json:"pipelines"`
+
+	runStarted time.Time
+	reportedAt time.Time
+	completed  bool
+	dirty      bool
+}
+
+func (cp *CollectionProgress) GetWritten() uint64 {
+
+	var written uint64
+	for _, count := range cp.Pipelines {
+		written += count
+	}
+
+	return written
+}
+
+func (cp *CollectionProgress) GetElapsed() time.Duration {
+	return cp.Elapsed + time.Since(cp.runStarted)
+}
+
+func (il *InitialLoader) loadProgress(collection string) (*CollectionProgress, error) {
+
+	data, err := il.store.Get("initial_load_progress", collection)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) == 0 {
+		return nil, nil
+	}
+
+	var progress CollectionProgress
+	err = json.Unmarshal(data, &progress)
+	if err != nil {
+		return nil, err
+	}
+
+	return &progress, nil
+}
+
+func (il *InitialLoader) saveProgress(cp *CollectionProgress) error {
+
+	// Elapsed time is accumulated if initial load was resumed
+	state := *cp
+	state.Elapsed = cp.GetElapsed()
+
+	data, err := json.Marshal(&state)
+	if err != nil {
+		return err
+	}
+
+	return il.store.Put("initial_load_progress", cp.Collection, data)
+}
+
+func (il *InitialLoader) beginCollection(collection string, rule *InitialLoadRule, tables []string) (*CollectionProgress, error) {
+
+	cp, err := il.loadProgress(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	// Snapshot is delivered from the beginning again after restarting, so records are counted again along
+	// with elapsed time, estimation which was made before table was truncated or cloned is kept
+	if cp != nil && rule.Resume {
+
+		log.WithFields(log.Fields{
+			"collection": collection,
+			"written":    cp.GetWritten(),
+			"estimated":  cp.Estimated,
+			"elapsed":    cp.Elapsed,
+			"startedAt":  cp.StartedAt,
+		}).Warn("Resuming initial load which was interrupted, records written before are merged by primary key")
+
+		cp.Pipelines = make(map[uint64]uint64)
+		cp.Elapsed = 0
+	} else {
+
+		if cp != nil {
+			log.WithFields(log.Fields{
+				"collection": collection,
+				"written":    cp.GetWritten(),
+			}).Warn("Restarting initial load which was interrupted")
+		}
+
+		cp = &CollectionProgress{
+			Collection: collection,
+			StartedAt:  time.Now(),
+			Estimated:  rule.EstimatedTotal,
+			Pipelines:  make(map[uint64]uint64),
+		}
+
+		// Estimating by statistics of target table
+		if cp.Estimated == 0 && len(tables) > 0 {
+			writer := il.subscriber.app.GetWriter()
+			estimated, err := writer.GetEstimatedRowCount(tables[0])
+			if err != nil {
+				log.WithFields(log.Fields{
+					"collection": collection,
+					"table":      tables[0],
+				}).Warn(err)
+			}
+
+			cp.Estimated = estimated
+		}
+
+		if cp.Estimated == 0 {
+			log.WithFields(log.Fields{
+				"collection": collection,
+			}).Warn("Number of records is unknown, estimatedTotal of rule is required for percentage of progress")
+		}
+	}
+
+	cp.runStarted = time.Now()
+	cp.reportedAt = time.Now()
+	cp.dirty = true
+
+	metrics.InitialLoadRowsEstimated.WithLabelValues(collection).Set(float64(cp.Estimated))
+	metrics.InitialLoadCompleted.WithLabelValues(collection).Set(0)
+
+	return cp, nil
+}
+
+// PrepareCollection is called before writing a snapshot record of the collection.
+func (il *InitialLoader) PrepareCollection(collection string, rule *InitialLoadRule, tables []string) error {
+
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+
+	cp, ok := il.progress[collection]
+	if ok && !cp.completed {
+		return nil
+	}
+
+	cp, err := il.beginCollection(collection, rule, tables)
+	if err != nil {
+		return err
+	}
+
+	il.progress[collection] = cp
+
+	return nil
+}
+
+// Track registers pipeline which is delivering snapshot records of the collection.
+func (il *InitialLoader) Track(pipelineID uint64, collection string) {
+
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+
+	cp, ok := il.progress[collection]
+	if !ok {
+		return
+	}
+
+	if _, ok := cp.Pipelines[pipelineID]; !ok {
+		cp.Pipelines[pipelineID] = 0
+	}
+}
+
+func (il *InitialLoader) written(pipelineID uint64, collection string) {
+
+	cp, ok := il.progress[collection]
+	if !ok {
+		return
+	}
+
+	cp.Pipelines[pipelineID]++
+	cp.dirty = true
+}
+
+func (il *InitialLoader) reportProgress(cp *CollectionProgress) {
+
+	written := cp.GetWritten()
+	elapsed := cp.GetElapsed()
+
+	fields := log.Fields{
+		"collection": cp.Collection,
+		"written":    written,
+		"estimated":  "unknown",
+		"elapsed":    elapsed.Truncate(time.Second).String(),
+	}
+
+	if cp.Estimated > 0 {
+		fields["estimated"] = cp.Estimated
+		fields["percent"] = float64(written) * 100 / float64(cp.Estimated)
+	}
+
+	if cp.completed {
+		log.WithFields(fields).Info("Initial load of collection completed")
+	} else {
+		log.WithFields(fields).Info("Initial load progress")
+	}
+
+	metrics.InitialLoadRowsWritten.WithLabelValues(cp.Collection).Set(float64(written))
+	metrics.InitialLoadElapsedSeconds.WithLabelValues(cp.Collection).Set(elapsed.Seconds())
+
+	cp.reportedAt = time.Now()
+}
+
+func (il *InitialLoader) isCollectionCompleted(cp *CollectionProgress) bool {
+
+	if len(cp.Pipelines) == 0 {
+		return false
+	}
+
+	for pipelineID := range cp.Pipelines {
+		if !il.isPipelineCompleted(pipelineID) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (il *InitialLoader) checkProgress() {
+
+	il.mutex.Lock()
+	defer il.mutex.Unlock()
+
+	for collection, cp := range il.progress {
+
+		if cp.completed {
+			continue
+		}
+
+		if il.isCollectionCompleted(cp) {
+
+			err := il.store.Delete("initial_load_progress", collection)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"collection": collection,
+				}).Error(err)
+				continue
+			}
+
+			cp.completed = true
+			il.reportProgress(cp)
+			metrics.InitialLoadCompleted.WithLabelValues(collection).Set(1)
+			continue
+		}
+
+		if cp.dirty {
+			err := il.saveProgress(cp)
+			if err != nil {
+				log.WithFields(log.Fields{
+					"collection": collection,
+				}).Error(err)
+			}
+
+			cp.dirty = false
+		}
+
+		if time.Since(cp.reportedAt) >= ProgressReportInterval {
+			il.reportProgress(cp)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package subscriber
+
+import (
+	"context"
+	"time"
+
+	gravity_subscriber "github.com/BrobridgeOrg/gravity-sdk/subscriber"
+	gravity_sdk_types_record "github.com/BrobridgeOrg/gravity-sdk/types/record"
+)
+
+// Reference is passed to writer along with records of message
+type Reference struct {
+	Message    *gravity_subscriber.Message
+	PipelineID uint64
+	Sequence   uint64
+	Context    context.Context
+
+	// Time of event which was read from record, snapshots have no event time
+	EventTime time.Time
+
+	// Time when event was received
+	ReceivedAt time.Time
+
+	// Event which was deferred during initial load has no message
+	deferredTable string
+	deferredID    uint64
+}
+
+func NewReference(ctx context.Context, msg *gravity_subscriber.Message) *Reference {
+
+	ref := &Reference{
+		Message: msg,
+		Context: ctx,
+	}
+
+	switch event := msg.Payload.(type) {
+	case *gravity_subscriber.DataEvent:
+		ref.PipelineID = event.PipelineID
+		ref.Sequence = event.Sequence
+		ref.ReceivedAt = time.Now()
+	case *gravity_subscriber.SnapshotEvent:
+		ref.PipelineID = event.PipelineID
+	}
+
+	return ref
+}
+
+func (ref *Reference) GetPipelineID() uint64 {
+	return ref.PipelineID
+}
+
+func (ref *Reference) GetSequence() uint64 {
+	return ref.Sequence
+}
+
+func (ref *Reference) GetContext() context.Context {
+	return ref.Context
+}
+
+func (ref *Reference) GetEventTime() time.Time {
+	return ref.EventTime
+}
+
+func (ref *Reference) GetReceivedAt() time.Time {
+	return ref.ReceivedAt
+}
+
+// getEventTime reads time of event from specific field of record, numbers are Unix time in milliseconds.
+func getEventTime(record *gravity_sdk_types_record.Record, fieldName string) (time.Time, bool) {
+
+	for _, field := range record.Fields {
+
+		if field.Name != fieldName {
+			continue
+		}
+
+		switch value := gravity_sdk_types_record.GetValue(field.Value).(type) {
+		case time.Time:
+			return value, true
+		case int64:
+			return time.Unix(0, value*int64(time.Millisecond)), true
+		case uint64:
+			return time.Unix(0, int64(value)*int64(time.Millisecond)), true
+		case float64:
+			return time.Unix(0, int64(value*float64(time.Millisecond))), true
+		case string:
+			t, err := time.Parse(time.RFC3339Nano, value)
+			if err == nil {
+				return t, true
+			}
+		}
+
+		return time.Time{}, false
+	}
+
+	return time.Time{}, false
+}
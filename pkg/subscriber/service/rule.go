@@ -1,7 +1,110 @@
 package subscriber
 
+import (
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/config"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database"
+)
+
 type SubscriptionConfig map[string][]string
 
+type InitialLoadStrategy string
+
+const (
+	InitialLoadStrategyAppend        InitialLoadStrategy = "append"
+	InitialLoadStrategyTruncateFirst InitialLoadStrategy = "truncate-first"
+	InitialLoadStrategyUpsert        InitialLoadStrategy = "upsert"
+	InitialLoadStrategyShadow        InitialLoadStrategy = "shadow"
+)
+
+type InitialLoadRule struct {
+	Strategy   InitialLoadStrategy     `json:"strategy"`
+	PrimaryKey string                  `json:"primaryKey"`
+	Shadow     *database.ShadowOptions `json:"shadow"`
+	Bulk       *database.BulkOptions   `json:"bulk"`
+
+	// Progress
+	Resume         bool   `json:"resume"`
+	EstimatedTotal uint64 `json:"estimatedTotal"`
+}
+
+type InitialLoadConfig map[string]*InitialLoadRule
+
 type RuleConfig struct {
 	Subscriptions SubscriptionConfig `json:"subscriptions"`
+	InitialLoad   InitialLoadConfig  `json:"initialLoad"`
+}
+
+var defaultInitialLoadRule = &InitialLoadRule{
+	Strategy: InitialLoadStrategyAppend,
+}
+
+func (config *RuleConfig) GetInitialLoadRule(collection string) *InitialLoadRule {
+
+	rule, ok := config.InitialLoad[collection]
+	if !ok {
+		return defaultInitialLoadRule
+	}
+
+	return rule
+}
+
+func (rules *RuleConfig) validateInitialLoad() error {
+
+	var problems config.Problems
+
+	for collection, rule := range rules.InitialLoad {
+
+		if rule == nil {
+			problems.Addf("initialLoad: invalid rule for collection \"%s\"", collection)
+			continue
+		}
+
+		if _, ok := rules.Subscriptions[collection]; !ok {
+			problems.Addf("initialLoad: collection \"%s\" is not subscribed", collection)
+		}
+
+		// Snapshot is delivered from the beginning again, so records are merged after resuming
+		if rule.Resume && len(rule.PrimaryKey) == 0 {
+			problems.Addf("initialLoad: primaryKey is required by resume for collection \"%s\"", collection)
+		}
+
+		switch rule.Strategy {
+		case "":
+			rule.Strategy = InitialLoadStrategyAppend
+		case InitialLoadStrategyAppend, InitialLoadStrategyTruncateFirst:
+		case InitialLoadStrategyUpsert:
+			if len(rule.PrimaryKey) == 0 {
+				problems.Addf("initialLoad: primaryKey is required by upsert strategy for collection \"%s\"", collection)
+			}
+		case InitialLoadStrategyShadow:
+			problems.Add(validateShadowOptions(collection, rule))
+		default:
+			problems.Addf("initialLoad: unknown strategy \"%s\" for collection \"%s\"", rule.Strategy, collection)
+		}
+	}
+
+	return problems.Err()
+}
+
+func validateShadowOptions(collection string, rule *InitialLoadRule) error {
+
+	var problems config.Problems
+
+	if rule.Shadow == nil {
+		rule.Shadow = &database.ShadowOptions{}
+	}
+
+	switch rule.Shadow.Method {
+	case "":
+		rule.Shadow.Method = database.ShadowMethodSynonym
+	case database.ShadowMethodSynonym:
+	default:
+		problems.Addf("initialLoad: unknown shadow method \"%s\" for collection \"%s\"", rule.Shadow.Method, collection)
+	}
+
+	if len(rule.Shadow.Tables) != 0 && len(rule.Shadow.Tables) != 2 {
+		problems.Addf("initialLoad: two tables are required by synonym for collection \"%s\"", collection)
+	}
+
+	return problems.Err()
 }
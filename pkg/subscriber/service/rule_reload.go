@@ -0,0 +1,106 @@
+package subscriber
+
+import (
+	"reflect"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/watcher"
+	log "github.com/sirupsen/logrus"
+)
+
+func (subscriber *Subscriber) getRules() *RuleConfig {
+
+	subscriber.rulesMutex.RLock()
+	defer subscriber.rulesMutex.RUnlock()
+
+	return subscriber.ruleConfig
+}
+
+func (subscriber *Subscriber) setRules(rules *RuleConfig) {
+
+	subscriber.rulesMutex.Lock()
+	defer subscriber.rulesMutex.Unlock()
+
+	subscriber.ruleConfig = rules
+}
+
+func (subscriber *Subscriber) watchRules() error {
+
+	w, err := watcher.Watch([]string{subscriber.config.RuleFile}, subscriber.reloadRules)
+	if err != nil {
+		return err
+	}
+
+	subscriber.ruleWatcher = w
+
+	return nil
+}
+
+// reloadRules loads rules file again, and old rules are kept if new ones are invalid.
+func (subscriber *Subscriber) reloadRules() {
+
+	subscriber.setupMutex.Lock()
+	defer subscriber.setupMutex.Unlock()
+
+	ruleFile := subscriber.config.RuleFile
+	rules, err := LoadRuleFile(ruleFile)
+	if err != nil {
+		log.WithFields(log.Fields{
+			"ruleFile": ruleFile,
+		}).Errorf("Failed to reload rules, old rules are still active: %v", err)
+		return
+	}
+
+	// Other files in the same directory were changed
+	old := subscriber.getRules()
+	if reflect.DeepEqual(old, rules) {
+		return
+	}
+
+	added, removed := diffSubscriptions(old.Subscriptions, rules.Subscriptions)
+
+	// Events of removed collections are still received from gravity, but they are no longer routed
+	if len(added) > 0 {
+		err = subscriber.subscriber.SubscribeToCollections(added)
+		if err != nil {
+			log.WithFields(log.Fields{
+				"ruleFile": ruleFile,
+			}).Errorf("Failed to subscribe to new collections, old rules are still active: %v", err)
+			return
+		}
+
+		// Snapshots are requested only when pipelines are initialized, existing records of new collections are not received
+		for collection := range added {
+			log.WithFields(log.Fields{
+				"collection": collection,
+			}).Warn("Collection was added by reloading, its snapshot is not received until pipelines were reset")
+		}
+	}
+
+	subscriber.setRules(rules)
+
+	log.WithFields(log.Fields{
+		"ruleFile": ruleFile,
+		"added":    len(added),
+		"removed":  len(removed),
+	}).Info("Reloaded rules")
+}
+
+// diffSubscriptions returns collections which were added and removed by new subscriptions.
+func diffSubscriptions(old SubscriptionConfig, new SubscriptionConfig) (SubscriptionConfig, []string) {
+
+	added := make(SubscriptionConfig)
+	for collection, tables := range new {
+		if _, ok := old[collection]; !ok {
+			added[collection] = tables
+		}
+	}
+
+	removed := make([]string, 0)
+	for collection := range old {
+		if _, ok := new[collection]; !ok {
+			removed = append(removed, collection)
+		}
+	}
+
+	return added, removed
+}
@@ -0,0 +1,108 @@
+package subscriber
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func newTestRuleSubscriber(t *testing.T, rules string) (*Subscriber, func(string)) {
+
+	dir, err := ioutil.TempDir("", "rules")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+
+	ruleFile := filepath.Join(dir, "subscriptions.json")
+	write := func(content string) {
+		err := ioutil.WriteFile(ruleFile, []byte(content), 0644)
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	write(rules)
+	ruleConfig, err := LoadRuleFile(ruleFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	subscriber := &Subscriber{
+		config: &Config{
+			RuleFile: ruleFile,
+		},
+		ruleConfig: ruleConfig,
+	}
+
+	return subscriber, write
+}
+
+func TestDiffSubscriptions(t *testing.T) {
+
+	old := SubscriptionConfig{
+		"users":    {"users"},
+		"accounts": {"accounts"},
+	}
+
+	new := SubscriptionConfig{
+		"users":  {"users", "users_history"},
+		"orders": {"orders"},
+	}
+
+	added, removed := diffSubscriptions(old, new)
+
+	// Tables of existing collections are routed by rules, they are not subscribed again
+	expected := SubscriptionConfig{
+		"orders": {"orders"},
+	}
+	if !reflect.DeepEqual(added, expected) {
+		t.Errorf("expected added %v, got %v", expected, added)
+	}
+
+	if !reflect.DeepEqual(removed, []string{"accounts"}) {
+		t.Errorf("expected removed [accounts], got %v", removed)
+	}
+
+	added, removed = diffSubscriptions(old, old)
+	if len(added) != 0 || len(removed) != 0 {
+		t.Errorf("expected no changes, got added %v, removed %v", added, removed)
+	}
+}
+
+func TestReloadRules(t *testing.T) {
+
+	subscriber, write := newTestRuleSubscriber(t, `{"subscriptions":{"users":["users"],"accounts":["accounts"]}}`)
+	old := subscriber.getRules()
+
+	// Invalid rules are ignored
+	invalid := []string{
+		`{"subscriptions":`,
+		`{"subscriptions":{}}`,
+		`{"subscriptions":{"users":[]}}`,
+		`{"subscriptions":{"users":["users"]},"initialLoad":{"orders":{"strategy":"append"}}}`,
+		`{"subscriptions":{"users":["users"]},"initialLoad":{"users":{"strategy":"upsert"}}}`,
+	}
+
+	for _, content := range invalid {
+		write(content)
+		subscriber.reloadRules()
+
+		if subscriber.getRules() != old {
+			t.Errorf("expected old rules to be active for %s", content)
+		}
+	}
+
+	// Collection was removed, nothing to subscribe
+	write(`{"subscriptions":{"users":["users","users_history"]}}`)
+	subscriber.reloadRules()
+
+	expected := SubscriptionConfig{
+		"users": {"users", "users_history"},
+	}
+	if !reflect.DeepEqual(subscriber.getRules().Subscriptions, expected) {
+		t.Errorf("expected %v, got %v", expected, subscriber.getRules().Subscriptions)
+	}
+}
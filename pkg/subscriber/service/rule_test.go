@@ -0,0 +1,41 @@
+package subscriber
+
+import (
+	"testing"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/config"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database"
+)
+
+func TestValidateInitialLoadReportsAllProblems(t *testing.T) {
+
+	rules := &RuleConfig{
+		Subscriptions: SubscriptionConfig{
+			"users": {"users"},
+		},
+		InitialLoad: map[string]*InitialLoadRule{
+			"users": {
+				Strategy: InitialLoadStrategyShadow,
+				Resume:   true,
+				Shadow: &database.ShadowOptions{
+					Method: "view",
+					Tables: []string{"USERS_A"},
+				},
+			},
+			"orders": {
+				Strategy: "replace",
+			},
+		},
+	}
+
+	err := rules.validateInitialLoad()
+	if err == nil {
+		t.Fatal("expected problems of initial load")
+	}
+
+	// Resume without primary key, shadow method and tables of users, and collection and strategy of orders
+	problems, ok := err.(config.Problems)
+	if !ok || len(problems) != 5 {
+		t.Errorf("expected 5 problems, got %v", err)
+	}
+}
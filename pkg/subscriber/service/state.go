@@ -1,28 +1,214 @@
 package subscriber
 
 import (
-	gravity_state_store "github.com/BrobridgeOrg/gravity-sdk/subscriber/state_store"
+	"fmt"
+	"sync"
+	"time"
+
+	gravity_subscriber "github.com/BrobridgeOrg/gravity-sdk/subscriber"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/state"
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/viper"
 )
 
-func (subscriber *Subscriber) InitStateStore() error {
+const (
+	StateBackendLocal  = "local"
+	StateBackendOracle = "oracle"
+)
+
+// Columns of states of transmitter
+var stateColumns = []string{
+	"initial_load",
+	"initial_load_progress",
+}
+
+type SequenceUpdateHandler func(uint64, uint64)
+
+// StateLoadHandler is called with sequence of pipeline once its state was loaded
+type StateLoadHandler func(uint64, uint64)
+
+type StateOptions struct {
+	Backend string
+
+	// Directory for local backend
+	Path string
+
+	// Table for oracle backend
+	Table         string
+	SubscriberID  string
+	FlushInterval time.Duration
+}
+
+func LoadStateOptions() *StateOptions {
+
+	viper.SetDefault("subscriber.stateBackend", StateBackendLocal)
+	viper.SetDefault("subscriber.stateTable", "GRAVITY_SUBSCRIBER_STATE")
+	viper.SetDefault("subscriber.stateFlushInterval", 1000)
+
+	return &StateOptions{
+		Backend:       viper.GetString("subscriber.stateBackend"),
+		Path:          viper.GetString("subscriber.stateStore"),
+		Table:         viper.GetString("subscriber.stateTable"),
+		SubscriberID:  viper.GetString("subscriber.subscriberID"),
+		FlushInterval: viper.GetDuration("subscriber.stateFlushInterval") * time.Millisecond,
+	}
+}
+
+func (options *StateOptions) Validate() error {
+
+	switch options.Backend {
+	case StateBackendLocal:
+		if len(options.Path) == 0 {
+			return fmt.Errorf("subscriber.stateStore: path is required by local backend")
+		}
+	case StateBackendOracle:
+		if len(options.Table) == 0 {
+			return fmt.Errorf("subscriber.stateTable: table is required by oracle backend")
+		}
+
+		if len(options.SubscriberID) == 0 {
+			return fmt.Errorf("subscriber.subscriberID: subscriber ID is required by oracle backend")
+		}
+
+		if options.FlushInterval <= 0 {
+			return fmt.Errorf("subscriber.stateFlushInterval: should be greater than 0")
+		}
+	default:
+		return fmt.Errorf("subscriber.stateBackend: unknown backend \"%s\"", options.Backend)
+	}
+
+	return nil
+}
+
+// NewStateBackend opens backend, oracle backend requires writer which was connected to database.
+func NewStateBackend(options *StateOptions, writer database.Writer) (state.Backend, error) {
+
+	if options.Backend == StateBackendOracle {
+		log.WithFields(log.Fields{
+			"table": options.Table,
+		}).Info("Loading state...")
+
+		return writer.NewStateBackend(options.Table, options.SubscriberID, options.FlushInterval)
+	}
 
-	storePath := viper.GetString("subscriber.stateStore")
 	log.WithFields(log.Fields{
-		"path": storePath,
+		"path": options.Path,
 	}).Info("Loading state...")
 
-	// Initializing state store
-	options := gravity_state_store.NewOptions()
-	options.Core.StoreOptions.DatabasePath = storePath
-	stateStore := gravity_state_store.NewStateStore(options)
-	err := stateStore.Initialize()
+	return state.NewLocalBackend(options.Path, stateColumns)
+}
+
+// StateStore provides states of pipelines for gravity subscriber.
+type StateStore struct {
+	backend   state.Backend
+	pipelines map[uint64]*PipelineState
+	mutex     sync.Mutex
+	handler   SequenceUpdateHandler
+	loaded    StateLoadHandler
+}
+
+type PipelineState struct {
+	stateStore *StateStore
+	pipelineID uint64
+	lastSeq    uint64
+}
+
+func (subscriber *Subscriber) InitStateStore(options *StateOptions) error {
+
+	backend, err := NewStateBackend(options, subscriber.app.GetWriter())
+	if err != nil {
+		return err
+	}
+
+	subscriber.stateBackend = backend
+	subscriber.stateStore = &StateStore{
+		backend:   backend,
+		pipelines: make(map[uint64]*PipelineState),
+		handler:   func(uint64, uint64) {},
+		loaded:    func(uint64, uint64) {},
+	}
+
+	return nil
+}
+
+func (ss *StateStore) SetSequenceUpdateHandler(fn SequenceUpdateHandler) {
+	ss.handler = fn
+}
+
+func (ss *StateStore) SetStateLoadHandler(fn StateLoadHandler) {
+	ss.loaded = fn
+}
+
+func (ss *StateStore) GetPipelineState(pipelineID uint64) (gravity_subscriber.PipelineState, error) {
+
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	if ps, ok := ss.pipelines[pipelineID]; ok {
+		return ps, nil
+	}
+
+	sequence, err := ss.backend.GetSequence(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+
+	ps := &PipelineState{
+		stateStore: ss,
+		pipelineID: pipelineID,
+		lastSeq:    sequence,
+	}
+
+	ss.pipelines[pipelineID] = ps
+	ss.loaded(pipelineID, sequence)
+
+	return ps, nil
+}
+
+func (ss *StateStore) GetPipelines() []uint64 {
+
+	ss.mutex.Lock()
+	defer ss.mutex.Unlock()
+
+	pipelines := make([]uint64, 0, len(ss.pipelines))
+	for pipelineID := range ss.pipelines {
+		pipelines = append(pipelines, pipelineID)
+	}
+
+	return pipelines
+}
+
+func (ps *PipelineState) GetLastSequence() uint64 {
+	return ps.lastSeq
+}
+
+func (ps *PipelineState) UpdateLastSequence(sequence uint64) error {
+
+	err := ps.stateStore.backend.PutSequence(ps.pipelineID, sequence)
 	if err != nil {
-		return nil
+		return err
 	}
 
-	subscriber.stateStore = stateStore
+	ps.lastSeq = sequence
+	ps.stateStore.handler(ps.pipelineID, sequence)
 
 	return nil
 }
+
+func (ps *PipelineState) Flush() error {
+	return ps.stateStore.backend.Flush()
+}
+
+// CloseStateStore saves states.
+func (subscriber *Subscriber) CloseStateStore() {
+
+	if subscriber.stateBackend == nil {
+		return
+	}
+
+	err := subscriber.stateBackend.Close()
+	if err != nil {
+		log.Error(err)
+	}
+}
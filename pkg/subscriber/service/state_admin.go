@@ -0,0 +1,121 @@
+package subscriber
+
+import (
+	"encoding/json"
+
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/state"
+)
+
+// State is a snapshot of everything in state store.
+type State struct {
+	Pipelines   map[uint64]uint64              `json:"pipelines"`
+	InitialLoad map[string]*InitialLoadState   `json:"initialLoad"`
+	Progress    map[string]*CollectionProgress `json:"progress"`
+}
+
+type ResetOptions struct {
+	// Empty means all pipelines in state store
+	Pipelines []uint64
+
+	// Events after this sequence will be received again
+	Sequence uint64
+
+	// Clear states of initial load, so interrupted initial load will not be resumed
+	InitialLoad bool
+}
+
+// ShowState reads all states in backend.
+func ShowState(backend state.Backend) (*State, error) {
+
+	pipelines, err := backend.ListSequences()
+	if err != nil {
+		return nil, err
+	}
+
+	s := &State{
+		Pipelines:   pipelines,
+		InitialLoad: make(map[string]*InitialLoadState),
+		Progress:    make(map[string]*CollectionProgress),
+	}
+
+	states, err := backend.List("initial_load")
+	if err != nil {
+		return nil, err
+	}
+
+	for table, data := range states {
+		var tableState InitialLoadState
+		err = json.Unmarshal(data, &tableState)
+		if err != nil {
+			return nil, err
+		}
+
+		s.InitialLoad[table] = &tableState
+	}
+
+	states, err = backend.List("initial_load_progress")
+	if err != nil {
+		return nil, err
+	}
+
+	for collection, data := range states {
+		var progress CollectionProgress
+		err = json.Unmarshal(data, &progress)
+		if err != nil {
+			return nil, err
+		}
+
+		s.Progress[collection] = &progress
+	}
+
+	return s, nil
+}
+
+// ResetState rewinds pipelines in backend.
+func ResetState(backend state.Backend, options *ResetOptions) error {
+
+	pipelines := options.Pipelines
+	if len(pipelines) == 0 {
+		sequences, err := backend.ListSequences()
+		if err != nil {
+			return err
+		}
+
+		for pipelineID := range sequences {
+			pipelines = append(pipelines, pipelineID)
+		}
+	}
+
+	for _, pipelineID := range pipelines {
+
+		var err error
+		if options.Sequence == 0 {
+			err = backend.DeleteSequence(pipelineID)
+		} else {
+			err = backend.PutSequence(pipelineID, options.Sequence)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	if options.InitialLoad {
+		for _, column := range stateColumns {
+
+			states, err := backend.List(column)
+			if err != nil {
+				return err
+			}
+
+			for key := range states {
+				err = backend.Delete(column, key)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	return backend.Flush()
+}
@@ -1,29 +1,45 @@
 package subscriber
 
 import (
-	"encoding/json"
-	"fmt"
-	"io/ioutil"
-	"os"
+	"context"
+	"sync"
+	"time"
 
 	"github.com/BrobridgeOrg/gravity-sdk/core"
 	"github.com/BrobridgeOrg/gravity-sdk/core/keyring"
 	gravity_subscriber "github.com/BrobridgeOrg/gravity-sdk/subscriber"
-	gravity_state_store "github.com/BrobridgeOrg/gravity-sdk/subscriber/state_store"
 	gravity_sdk_types_record "github.com/BrobridgeOrg/gravity-sdk/types/record"
 	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/app"
 	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/database"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/retry"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/secret"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/state"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/tracing"
+	"github.com/BrobridgeOrg/gravity-transmitter-oracle/pkg/watcher"
 	"github.com/jinzhu/copier"
 	log "github.com/sirupsen/logrus"
-	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel/attribute"
 )
 
 type Subscriber struct {
 	app               app.App
-	stateStore        *gravity_state_store.StateStore
+	config            *Config
+	stateStore        *StateStore
+	stateBackend      state.Backend
 	subscriber        *gravity_subscriber.Subscriber
+	options           *gravity_subscriber.Options
 	ruleConfig        *RuleConfig
+	rulesMutex        sync.RWMutex
+	setupMutex        sync.Mutex
+	ruleWatcher       *watcher.Watcher
+	keyWatcher        *watcher.Watcher
+	coordinator       *Coordinator
+	initialLoader     *InitialLoader
+	flowControl       *FlowControl
+	retryPolicy       *retry.Policy
 	completionCounter map[*gravity_subscriber.Message]int
+	completionMutex   sync.Mutex
+	missingEventTime  sync.Map
 }
 
 func NewSubscriber(a app.App) *Subscriber {
@@ -33,20 +49,37 @@ func NewSubscriber(a app.App) *Subscriber {
 	}
 }
 
-func (subscriber *Subscriber) processData(msg *gravity_subscriber.Message) error {
+func (subscriber *Subscriber) processData(ctx context.Context, msg *gravity_subscriber.Message) error {
 
 	event := msg.Payload.(*gravity_subscriber.DataEvent)
 	record := event.Payload
 
 	// Getting tables for specific collection
-	tables, ok := subscriber.ruleConfig.Subscriptions[record.Table]
+	tables, ok := subscriber.getRules().Subscriptions[record.Table]
 	if !ok {
 		// skip
+		subscriber.flowControl.Release(msg)
 		return nil
 	}
 
 	//	log.Info(string(msg.Event.Data))
 
+	ref := NewReference(ctx, msg)
+
+	// Lag is measured from time of source if record carries it
+	if len(subscriber.config.EventTimeField) > 0 {
+		if eventTime, ok := getEventTime(record, subscriber.config.EventTimeField); ok {
+			ref.EventTime = eventTime
+		} else if _, warned := subscriber.missingEventTime.LoadOrStore(record.Table, true); !warned {
+			log.WithFields(log.Fields{
+				"collection": record.Table,
+				"field":      subscriber.config.EventTimeField,
+			}).Warn("Records carry no time of event, lag of collection is not measured")
+		}
+	}
+
+	rule := subscriber.getRules().GetInitialLoadRule(record.Table)
+
 	// Save record to each table
 	writer := subscriber.app.GetWriter()
 	for _, tableName := range tables {
@@ -54,106 +87,130 @@ func (subscriber *Subscriber) processData(msg *gravity_subscriber.Message) error
 		copier.Copy(&rs, record)
 		rs.Table = tableName
 
+		// Table is being loaded into shadow table
+		deferred := false
+		for {
+			d, err := subscriber.initialLoader.Defer(ref, &rs, rule)
+			if err == nil {
+				deferred = d
+				break
+			}
+
+			log.WithFields(log.Fields{
+				"table": tableName,
+			}).Error(err)
+
+			<-time.After(time.Second * 5)
+		}
+
+		// Deferred event was saved on disk already
+		if deferred {
+			subscriber.complete(ref, tables)
+			continue
+		}
+
 		// TODO: using batch mechanism to improve performance
+		backoff := subscriber.retryPolicy.NewBackoff()
 		for {
-			err := writer.ProcessData(msg, &rs, tables)
+			err := writer.ProcessData(ref, &rs, tables)
 			if err == nil {
 				break
 			}
 
 			log.Error(err)
+
+			if !backoff.Wait() {
+				writer.Reject(ref, &rs, tables, err)
+				break
+			}
 		}
 	}
 
 	return nil
 }
 
-func (subscriber *Subscriber) LoadConfigFile(filename string) (*RuleConfig, error) {
+func (subscriber *Subscriber) Init() error {
 
-	// Open and read config file
-	jsonFile, err := os.Open(filename)
+	// Load configuration and rules
+	config, err := LoadConfig()
 	if err != nil {
-		return nil, err
+		return err
 	}
 
-	defer jsonFile.Close()
-
-	byteValue, _ := ioutil.ReadAll(jsonFile)
-
-	// Parse config
-	var config RuleConfig
-	json.Unmarshal(byteValue, &config)
-
-	return &config, nil
-}
-
-func (subscriber *Subscriber) Init() error {
+	log.WithFields(log.Fields{
+		"ruleFile": config.RuleFile,
+	}).Info("Loaded rules")
 
-	// Load rules
-	ruleFile := viper.GetString("rules.subscription")
+	subscriber.config = config
+	subscriber.ruleConfig = config.Rules
 
-	log.WithFields(log.Fields{
-		"ruleFile": ruleFile,
-	}).Info("Loading rules...")
+	if len(config.EventTimeField) == 0 {
+		log.Warn("subscriber.eventTimeField is not set, replication lag is not measured and only latency of transmitter is reported")
+	}
 
-	ruleConfig, err := subscriber.LoadConfigFile(ruleFile)
+	// Load state
+	err = subscriber.InitStateStore(config.State)
 	if err != nil {
 		return err
 	}
 
-	subscriber.ruleConfig = ruleConfig
-
-	// Load state
-	err = subscriber.InitStateStore()
+	// Initializing initial load
+	subscriber.initialLoader = NewInitialLoader(subscriber, subscriber.stateBackend, config.InitialLoadDeferredPath)
+	err = subscriber.initialLoader.Init()
 	if err != nil {
 		return err
 	}
 
+	subscriber.stateStore.SetSequenceUpdateHandler(func(pipelineID uint64, sequence uint64) {
+		// Snapshot was completed if pipeline starts updating sequence
+		subscriber.initialLoader.Fetched(pipelineID)
+	})
+	subscriber.stateStore.SetStateLoadHandler(func(pipelineID uint64, sequence uint64) {
+		// Pipeline without state delivers snapshot, events should be deferred for shadow tables from now on
+		if config.InitialLoadEnabled && sequence == 0 {
+			subscriber.initialLoader.Expect(pipelineID)
+		}
+	})
+
 	// Initializing writer
 	writer := subscriber.app.GetWriter()
 	writer.SetCompletionHandler(func(cmd database.DBCommand) {
-		// Ack after writing to database
-		ref := cmd.GetReference()
-		msg := ref.(*gravity_subscriber.Message)
+		ref := cmd.GetReference().(*Reference)
 
-		tables := cmd.GetTables()
-
-		subscriber.completionCounter[msg] += 1
-		if subscriber.completionCounter[msg] == len(tables) {
-			delete(subscriber.completionCounter, msg)
-			msg.Ack()
+		// Event which was deferred during initial load
+		if ref.Message == nil {
+			subscriber.initialLoader.Applied(ref)
+			return
 		}
+
+		// Ack after writing to database
+		subscriber.complete(ref, cmd.GetTables())
 	})
 
-	// Initializing gravity node information
-	viper.SetDefault("gravity.domain", "gravity")
-	domain := viper.GetString("gravity.domain")
-	host := viper.GetString("gravity.host")
+	subscriber.retryPolicy = config.RetryPolicy
+
+	// Initializing flow control
+	subscriber.flowControl = NewFlowControl(config.MaxInflight, config.ResumeInflight)
 
 	log.WithFields(log.Fields{
-		"host": host,
+		"host": config.Host,
 	}).Info("Initializing gravity subscriber")
 
 	// Initializing gravity subscriber and connecting to server
-	viper.SetDefault("subscriber.workerCount", 4)
-	viper.SetDefault("subscriber.chunkSize", 2048)
 	options := gravity_subscriber.NewOptions()
-	options.Verbose = viper.GetBool("subscriber.verbose")
-	options.Domain = domain
+	options.Verbose = config.Verbose
+	options.Domain = config.Domain
 	options.StateStore = subscriber.stateStore
-	options.WorkerCount = viper.GetInt("subscriber.workerCount")
-	options.ChunkSize = viper.GetInt("subscriber.chunkSize")
-	options.InitialLoad.Enabled = viper.GetBool("initialLoad.enabled")
-	options.InitialLoad.OmittedCount = viper.GetUint64("initialLoad.omittedCount")
-
-	// Loading access key
-	viper.SetDefault("subscriber.appID", "anonymous")
-	viper.SetDefault("subscriber.accessKey", "")
-	options.Key = keyring.NewKey(viper.GetString("subscriber.appID"), viper.GetString("subscriber.accessKey"))
+	options.WorkerCount = config.WorkerCount
+	options.ChunkSize = config.ChunkSize
+	options.InitialLoad.Enabled = config.InitialLoadEnabled
+	options.InitialLoad.OmittedCount = config.InitialLoadOmittedCount
+	options.Key = keyring.NewKey(config.AppID, config.AccessKey)
 
+	subscriber.options = options
 	subscriber.subscriber = gravity_subscriber.NewSubscriber(options)
 	opts := core.NewOptions()
-	err = subscriber.subscriber.Connect(host, opts)
+	err = subscriber.subscriber.Connect(config.Host, opts)
 	if err != nil {
 		return err
 	}
@@ -164,25 +221,57 @@ func (subscriber *Subscriber) Init() error {
 
 	// Register subscriber
 	log.Info("Registering subscriber")
-	subscriberID := viper.GetString("subscriber.subscriberID")
-	subscriberName := viper.GetString("subscriber.subscriberName")
-	err = subscriber.subscriber.Register(gravity_subscriber.SubscriberType_Transmitter, "oracle", subscriberID, subscriberName)
+	err = subscriber.subscriber.Register(gravity_subscriber.SubscriberType_Transmitter, "oracle", config.SubscriberID, config.SubscriberName)
 	if err != nil {
 		return err
 	}
 
 	// Subscribe to collections
-	err = subscriber.subscriber.SubscribeToCollections(subscriber.ruleConfig.Subscriptions)
+	err = subscriber.subscriber.SubscribeToCollections(subscriber.getRules().Subscriptions)
 	if err != nil {
 		return err
 	}
 
 	// Subscribe to pipelines
-	err = subscriber.initializePipelines()
+	if config.Coordination.Enabled {
+		err = subscriber.initializeCoordinator()
+	} else {
+		err = subscriber.initializePipelines()
+	}
+
 	if err != nil {
 		return err
 	}
 
+	err = subscriber.watchAccessKey()
+	if err != nil {
+		return err
+	}
+
+	// Rules will be reloaded once its file was changed
+	return subscriber.watchRules()
+}
+
+// initializeCoordinator subscribes to pipelines which were assigned by coordinator, range of pipelines is ignored.
+func (subscriber *Subscriber) initializeCoordinator() error {
+
+	options := subscriber.config.Coordination
+
+	// Leases are grouped by subscriber, so transmitters of the same subscriber share pipelines
+	store, err := subscriber.app.GetWriter().NewLeaseStore(options.Table, subscriber.config.SubscriberID)
+	if err != nil {
+		return err
+	}
+
+	coordinator := NewCoordinator(subscriber, store, options)
+	err = coordinator.Start()
+	if err != nil {
+		store.Close()
+		return err
+	}
+
+	subscriber.coordinator = coordinator
+
 	return nil
 }
 
@@ -190,11 +279,8 @@ func (subscriber *Subscriber) initializePipelines() error {
 
 	// Subscribe to pipelines
 	log.WithFields(log.Fields{}).Info("Subscribing to gravity pipelines...")
-	viper.SetDefault("subscriber.pipelineStart", 0)
-	viper.SetDefault("subscriber.pipelineEnd", -1)
-
-	pipelineStart := viper.GetInt64("subscriber.pipelineStart")
-	pipelineEnd := viper.GetInt64("subscriber.pipelineEnd")
+	pipelineStart := subscriber.config.PipelineStart
+	pipelineEnd := subscriber.config.PipelineEnd
 
 	// Subscribe to all pipelines
 	if pipelineStart == 0 && pipelineEnd == -1 {
@@ -207,16 +293,6 @@ func (subscriber *Subscriber) initializePipelines() error {
 	}
 
 	// Subscribe to pipelines in then range
-	if pipelineStart < 0 {
-		return fmt.Errorf("subscriber.pipelineStart should be higher than -1")
-	}
-
-	if pipelineStart > pipelineEnd {
-		if pipelineEnd != -1 {
-			return fmt.Errorf("subscriber.pipelineStart should be less than subscriber.pipelineEnd")
-		}
-	}
-
 	count, err := subscriber.subscriber.GetPipelineCount()
 	if err != nil {
 		return err
@@ -239,9 +315,55 @@ func (subscriber *Subscriber) initializePipelines() error {
 	return nil
 }
 
+// complete acks message once record was written to all tables.
+func (subscriber *Subscriber) complete(ref *Reference, tables []string) {
+
+	msg := ref.Message
+
+	subscriber.completionMutex.Lock()
+	defer subscriber.completionMutex.Unlock()
+
+	subscriber.completionCounter[msg] += 1
+	if subscriber.completionCounter[msg] == len(tables) {
+		delete(subscriber.completionCounter, msg)
+
+		if msg.Type == gravity_subscriber.MESSAGE_TYPE_SNAPSHOT {
+			event := msg.Payload.(*gravity_subscriber.SnapshotEvent)
+			subscriber.initialLoader.Written(event.PipelineID, event.Collection)
+		}
+
+		// Time from being written to acknowledged
+		_, span := tracing.Start(ref.Context, "Subscriber.ack")
+		subscriber.ack(msg)
+		span.End()
+	}
+}
+
+func (subscriber *Subscriber) ack(msg *gravity_subscriber.Message) {
+
+	// Message object will be recycled after acknowledging
+	subscriber.flowControl.Release(msg)
+	msg.Ack()
+}
+
 func (subscriber *Subscriber) eventHandler(msg *gravity_subscriber.Message) {
 
-	err := subscriber.processData(msg)
+	event := msg.Payload.(*gravity_subscriber.DataEvent)
+
+	// Every event starts a trace, and spans of writing and acknowledging are its children
+	ctx, span := tracing.Start(context.Background(), "Subscriber.eventHandler",
+		attribute.Int64("pipeline", int64(event.PipelineID)),
+		attribute.Int64("sequence", int64(event.Sequence)),
+		attribute.String("collection", event.Payload.Table),
+	)
+
+	subscriber.flowControl.Acquire(msg)
+
+	// Events are coming after snapshot
+	subscriber.initialLoader.Fetched(event.PipelineID)
+
+	err := subscriber.processData(ctx, msg)
+	tracing.End(span, err)
 	if err != nil {
 		log.Error(err)
 		return
@@ -250,42 +372,174 @@ func (subscriber *Subscriber) eventHandler(msg *gravity_subscriber.Message) {
 
 func (subscriber *Subscriber) snapshotHandler(msg *gravity_subscriber.Message) {
 
+	subscriber.flowControl.Acquire(msg)
+
 	event := msg.Payload.(*gravity_subscriber.SnapshotEvent)
 	snapshotRecord := event.Payload
 
 	// Getting tables for specific collection
-	tables, ok := subscriber.ruleConfig.Subscriptions[event.Collection]
+	rules := subscriber.getRules()
+	tables, ok := rules.Subscriptions[event.Collection]
 	if !ok {
+		subscriber.flowControl.Release(msg)
 		return
 	}
 
+	rule := rules.GetInitialLoadRule(event.Collection)
+
+	// Preparing for initial load
+	for {
+		err := subscriber.initialLoader.PrepareCollection(event.Collection, rule, tables)
+		if err == nil {
+			break
+		}
+
+		log.WithFields(log.Fields{
+			"collection": event.Collection,
+		}).Error(err)
+
+		<-time.After(time.Second * 5)
+	}
+
+	targets := make([]string, len(tables))
+	modes := make([]WriteMode, len(tables))
+	for i, tableName := range tables {
+		for {
+			t, m, err := subscriber.initialLoader.Prepare(event.PipelineID, tableName, rule)
+			if err == nil {
+				targets[i] = t
+				modes[i] = m
+				break
+			}
+
+			log.WithFields(log.Fields{
+				"table": tableName,
+			}).Error(err)
+
+			<-time.After(time.Second * 5)
+		}
+	}
+
+	subscriber.initialLoader.Track(event.PipelineID, event.Collection)
+
 	// Prepare record for database writer
 	var record gravity_sdk_types_record.Record
 	record.Method = gravity_sdk_types_record.Method_INSERT
 	record.Fields = snapshotRecord.Payload.Map.Fields
 
+	ref := NewReference(context.Background(), msg)
+
+	subscriber.initialLoader.Received(event.PipelineID)
+
 	// Save record to each table
 	writer := subscriber.app.GetWriter()
-	for _, tableName := range tables {
+	for i := range tables {
 		var rs gravity_sdk_types_record.Record
-		copier.Copy(&rs, record)
-		rs.Table = tableName
+		copier.Copy(&rs, &record)
+		rs.Table = targets[i]
 
 		// TODO: using batch mechanism to improve performance
+		backoff := subscriber.retryPolicy.NewBackoff()
 		for {
-			err := writer.ProcessData(msg, &rs, tables)
+			var err error
+			switch modes[i] {
+			case WriteModeUpsert:
+				rs.PrimaryKey = rule.PrimaryKey
+				err = writer.UpsertRecord(ref, &rs, tables)
+			case WriteModeBulk:
+				err = writer.BulkInsertRecord(ref, &rs, tables)
+			default:
+				err = writer.ProcessData(ref, &rs, tables)
+			}
+
 			if err == nil {
 				break
 			}
 
 			log.Error(err)
+
+			if !backoff.Wait() {
+				writer.Reject(ref, &rs, tables, err)
+				break
+			}
 		}
 	}
 }
 
 func (subscriber *Subscriber) Run() error {
 
+	go subscriber.initialLoader.Run()
+
 	subscriber.subscriber.Start()
 
 	return nil
 }
+
+// watchAccessKey reloads access key once its file was changed.
+func (subscriber *Subscriber) watchAccessKey() error {
+
+	w, err := secret.Watch([]string{"subscriber.accessKey"}, subscriber.reloadAccessKey)
+	if err != nil {
+		return err
+	}
+
+	subscriber.keyWatcher = w
+
+	return nil
+}
+
+// reloadAccessKey reads access key again, and requests are sent to gravity with new key if it was changed.
+func (subscriber *Subscriber) reloadAccessKey() {
+
+	accessKey, err := secret.Get("subscriber.accessKey")
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if accessKey == subscriber.config.AccessKey {
+		return
+	}
+
+	log.WithFields(log.Fields{
+		"file": secret.GetFile("subscriber.accessKey"),
+	}).Warn("Access key of gravity was changed, reconnecting with new access key")
+
+	// Key is read from options by SDK for every request, so it is replaced rather than being modified in place
+	subscriber.config.AccessKey = accessKey
+	subscriber.options.Key = keyring.NewKey(subscriber.config.AppID, accessKey)
+}
+
+// Stop stops receiving events from gravity.
+func (subscriber *Subscriber) Stop() {
+
+	if subscriber.ruleWatcher != nil {
+		subscriber.ruleWatcher.Close()
+	}
+
+	if subscriber.keyWatcher != nil {
+		subscriber.keyWatcher.Close()
+	}
+
+	if subscriber.coordinator != nil {
+		subscriber.coordinator.Stop()
+	}
+
+	if subscriber.subscriber == nil {
+		return
+	}
+
+	subscriber.subscriber.Disconnect()
+}
+
+// Close saves states, it should be called after writer was closed so that
+// events which were written are acknowledged.
+func (subscriber *Subscriber) Close() {
+
+	subscriber.CloseStateStore()
+
+	// Pipelines are released after states were saved
+	if subscriber.coordinator != nil {
+		subscriber.coordinator.Close()
+	}
+}
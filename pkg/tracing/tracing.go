@@ -0,0 +1,164 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/viper"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.4.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	ExporterNone = "none"
+	ExporterOTLP = "otlp"
+)
+
+// Tracer is resolved by global provider once it was initialized, so spans are dropped before that.
+var tracer = otel.Tracer("github.com/BrobridgeOrg/gravity-transmitter-oracle")
+
+type Options struct {
+	Exporter    string
+	Endpoint    string
+	Insecure    bool
+	SampleRatio float64
+	ServiceName string
+}
+
+func LoadOptions() *Options {
+
+	viper.SetDefault("tracing.exporter", ExporterNone)
+	viper.SetDefault("tracing.endpoint", "localhost:4317")
+	viper.SetDefault("tracing.sampleRatio", 1.0)
+	viper.SetDefault("tracing.serviceName", "gravity-transmitter-oracle")
+
+	return &Options{
+		Exporter:    viper.GetString("tracing.exporter"),
+		Endpoint:    viper.GetString("tracing.endpoint"),
+		Insecure:    viper.GetBool("tracing.insecure"),
+		SampleRatio: viper.GetFloat64("tracing.sampleRatio"),
+		ServiceName: viper.GetString("tracing.serviceName"),
+	}
+}
+
+func (options *Options) Validate() error {
+
+	switch options.Exporter {
+	case ExporterNone:
+		return nil
+	case ExporterOTLP:
+	default:
+		return fmt.Errorf("tracing.exporter: should be \"%s\" or \"%s\"", ExporterNone, ExporterOTLP)
+	}
+
+	if len(options.Endpoint) == 0 {
+		return fmt.Errorf("tracing.endpoint: endpoint is required")
+	}
+
+	if options.SampleRatio < 0 || options.SampleRatio > 1 {
+		return fmt.Errorf("tracing.sampleRatio: should be between 0 and 1")
+	}
+
+	return nil
+}
+
+// Init exports spans by options, spans are dropped if there is no exporter. The returned function
+// flushes spans which were not exported yet.
+func Init(options *Options) (func(), error) {
+
+	err := options.Validate()
+	if err != nil {
+		return nil, err
+	}
+
+	if options.Exporter == ExporterNone {
+		return func() {}, nil
+	}
+
+	clientOptions := []otlptracegrpc.Option{
+		otlptracegrpc.WithEndpoint(options.Endpoint),
+	}
+
+	if options.Insecure {
+		clientOptions = append(clientOptions, otlptracegrpc.WithInsecure())
+	}
+
+	// Connection is made in background, so collector is not required to be ready
+	exporter, err := otlptracegrpc.New(context.Background(), clientOptions...)
+	if err != nil {
+		return nil, err
+	}
+
+	log.WithFields(log.Fields{
+		"endpoint": options.Endpoint,
+	}).Info("Exporting spans by OTLP")
+
+	provider := newProvider(sdktrace.NewBatchSpanProcessor(exporter), options)
+
+	return func() {
+		err := provider.Shutdown(context.Background())
+		if err != nil {
+			log.Error(err)
+		}
+	}, nil
+}
+
+// InitWithExporter sets global provider which exports spans to specific exporter synchronously,
+// e.g. in-memory exporter for tests.
+func InitWithExporter(exporter sdktrace.SpanExporter, options *Options) *sdktrace.TracerProvider {
+	return newProvider(sdktrace.NewSimpleSpanProcessor(exporter), options)
+}
+
+func newProvider(processor sdktrace.SpanProcessor, options *Options) *sdktrace.TracerProvider {
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithSpanProcessor(processor),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceNameKey.String(options.ServiceName),
+		)),
+	)
+
+	otel.SetTracerProvider(provider)
+
+	return provider
+}
+
+// Start starts span as a child of span in context.
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// StartWithLinks starts a new trace which is linked to spans of contexts, it is used by
+// operations for multiple events, e.g. a batch of commands.
+func StartWithLinks(name string, contexts []context.Context, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+
+	links := make([]trace.Link, 0, len(contexts))
+	for _, ctx := range contexts {
+		sc := trace.SpanContextFromContext(ctx)
+		if sc.IsValid() {
+			links = append(links, trace.Link{SpanContext: sc})
+		}
+	}
+
+	return tracer.Start(context.Background(), name, trace.WithAttributes(attrs...), trace.WithLinks(links...))
+}
+
+// End ends span, and error is recorded if it is not nil.
+func End(span trace.Span, err error) {
+
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+
+	span.End()
+}
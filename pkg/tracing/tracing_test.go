@@ -0,0 +1,79 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+func TestSpans(t *testing.T) {
+
+	exporter := tracetest.NewInMemoryExporter()
+	provider := InitWithExporter(exporter, &Options{
+		SampleRatio: 1,
+		ServiceName: "test",
+	})
+
+	ctx, event := Start(context.Background(), "Subscriber.eventHandler", attribute.Int64("pipeline", 3))
+	_, write := Start(ctx, "Writer.ProcessData")
+	End(write, errors.New("ORA-00942: table or view does not exist"))
+
+	_, batch := StartWithLinks("Writer.execBatch", []context.Context{ctx, context.Background()})
+	End(batch, nil)
+	event.End()
+
+	spans := make(map[string]tracetest.SpanStub)
+	for _, span := range exporter.GetSpans() {
+		spans[span.Name] = span
+	}
+
+	if len(spans) != 3 {
+		t.Fatalf("expected 3 spans, got %d", len(spans))
+	}
+
+	root := spans["Subscriber.eventHandler"]
+	if spans["Writer.ProcessData"].Parent.SpanID() != root.SpanContext.SpanID() {
+		t.Errorf("Writer.ProcessData: expected to be a child of event")
+	}
+
+	if spans["Writer.ProcessData"].Status.Code != codes.Error {
+		t.Errorf("Writer.ProcessData: expected error status, got %v", spans["Writer.ProcessData"].Status.Code)
+	}
+
+	// Batch starts a new trace, and it is linked to valid spans only
+	b := spans["Writer.execBatch"]
+	if b.Parent.IsValid() {
+		t.Errorf("Writer.execBatch: expected no parent")
+	}
+
+	if len(b.Links) != 1 || b.Links[0].SpanContext.SpanID() != root.SpanContext.SpanID() {
+		t.Errorf("Writer.execBatch: expected to be linked to event, got %v", b.Links)
+	}
+
+	provider.Shutdown(context.Background())
+}
+
+func TestValidate(t *testing.T) {
+
+	tests := []struct {
+		options *Options
+		valid   bool
+	}{
+		{&Options{Exporter: ExporterNone}, true},
+		{&Options{Exporter: ExporterOTLP, Endpoint: "localhost:4317", SampleRatio: 0.5}, true},
+		{&Options{Exporter: ExporterOTLP, SampleRatio: 1}, false},
+		{&Options{Exporter: ExporterOTLP, Endpoint: "localhost:4317", SampleRatio: 2}, false},
+		{&Options{Exporter: "zipkin"}, false},
+	}
+
+	for _, test := range tests {
+		err := test.options.Validate()
+		if (err == nil) != test.valid {
+			t.Errorf("%+v: expected valid to be %v, got %v", test.options, test.valid, err)
+		}
+	}
+}
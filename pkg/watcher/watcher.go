@@ -0,0 +1,123 @@
+package watcher
+
+import (
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	log "github.com/sirupsen/logrus"
+)
+
+// Delay for waiting files are all updated (e.g. Kubernetes swaps secret by symlink)
+var ReloadDelay = time.Second
+
+// Watcher calls handler once watched files were changed.
+type Watcher struct {
+	watcher *fsnotify.Watcher
+	handler func()
+	timer   *time.Timer
+	mutex   sync.Mutex
+}
+
+// Watch watches specific files, nil will be returned if no file was specified.
+func Watch(filenames []string, handler func()) (*Watcher, error) {
+
+	files := make(map[string]bool)
+	for _, filename := range filenames {
+		path, err := filepath.Abs(filename)
+		if err != nil {
+			return nil, err
+		}
+
+		files[path] = true
+	}
+
+	if len(files) == 0 {
+		return nil, nil
+	}
+
+	fw, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	// Watching directory because file might be replaced rather than modified, all changes
+	// in directory are reported and handler should tell whether files were changed
+	dirs := make(map[string]bool)
+	for path := range files {
+		dir := filepath.Dir(path)
+		if dirs[dir] {
+			continue
+		}
+
+		err := fw.Add(dir)
+		if err != nil {
+			fw.Close()
+			return nil, err
+		}
+
+		dirs[dir] = true
+
+		log.WithFields(log.Fields{
+			"path": dir,
+		}).Info("Watching files")
+	}
+
+	w := &Watcher{
+		watcher: fw,
+		handler: handler,
+	}
+
+	go w.run()
+
+	return w, nil
+}
+
+func (w *Watcher) run() {
+
+	for {
+		select {
+		case event, ok := <-w.watcher.Events:
+			if !ok {
+				return
+			}
+
+			if event.Op == fsnotify.Chmod {
+				continue
+			}
+
+			w.schedule()
+
+		case err, ok := <-w.watcher.Errors:
+			if !ok {
+				return
+			}
+
+			log.Error(err)
+		}
+	}
+}
+
+func (w *Watcher) schedule() {
+
+	w.mutex.Lock()
+	defer w.mutex.Unlock()
+
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+
+	w.timer = time.AfterFunc(ReloadDelay, w.handler)
+}
+
+func (w *Watcher) Close() error {
+
+	w.mutex.Lock()
+	if w.timer != nil {
+		w.timer.Stop()
+	}
+	w.mutex.Unlock()
+
+	return w.watcher.Close()
+}